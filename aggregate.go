@@ -0,0 +1,139 @@
+package hades
+
+import (
+	"fmt"
+	"reflect"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// Sum computes the sum of column for all records of model matching cond,
+// and stores it in dest, which must be a pointer. It returns false and leaves
+// dest untouched if there are no matching records.
+func (c *Context) Sum(conn *sqlite.Conn, model interface{}, column string, dest interface{}, cond builder.Cond, search Search) (bool, error) {
+	return c.aggregate(conn, "sum", model, column, dest, cond, search, true)
+}
+
+// Min finds the lowest value of column for all records of model matching
+// cond, and stores it in dest, which must be a pointer. The value is decoded
+// like the field is, so for example a time.Time column can be read into a
+// *time.Time. It returns false and leaves dest untouched if there are no
+// matching records.
+func (c *Context) Min(conn *sqlite.Conn, model interface{}, column string, dest interface{}, cond builder.Cond, search Search) (bool, error) {
+	return c.aggregate(conn, "min", model, column, dest, cond, search, true)
+}
+
+// Max is like Min, but finds the highest value.
+func (c *Context) Max(conn *sqlite.Conn, model interface{}, column string, dest interface{}, cond builder.Cond, search Search) (bool, error) {
+	return c.aggregate(conn, "max", model, column, dest, cond, search, true)
+}
+
+// Avg computes the average of column for all records of model matching cond,
+// and stores it in dest, which is typically a *float64. It returns false and
+// leaves dest untouched if there are no matching records.
+func (c *Context) Avg(conn *sqlite.Conn, model interface{}, column string, dest interface{}, cond builder.Cond, search Search) (bool, error) {
+	return c.aggregate(conn, "avg", model, column, dest, cond, search, false)
+}
+
+func (c *Context) aggregate(conn *sqlite.Conn, fn string, model interface{}, column string, dest interface{}, cond builder.Cond, search Search, decodeAsField bool) (bool, error) {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() {
+		return false, errors.Errorf("%s expects dest to be a non-nil pointer, but it got a %v", fn, reflect.TypeOf(dest))
+	}
+
+	if len(search.groups) > 0 || len(search.having) > 0 {
+		return false, errors.Errorf("%s can't be used with a search that groups rows", fn)
+	}
+
+	ms := c.NewScope(model).GetModelStruct()
+	sf, err := ms.columnField(column)
+	if err != nil {
+		return false, err
+	}
+
+	expr := fmt.Sprintf("%s(%s.%s)", fn, EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName))
+	if search.distinct {
+		expr = fmt.Sprintf("%s(DISTINCT %s.%s)", fn, EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName))
+		search.distinct = false
+	}
+
+	search, err = search.withoutPaging().resolveFields(c, ms)
+	if err != nil {
+		return false, err
+	}
+
+	b := builder.Select(expr).From(ms.TableName).Where(cond)
+	query, args, err := search.ToSQL(b)
+	if err != nil {
+		return false, err
+	}
+
+	decodeField := sf
+	if !decodeAsField {
+		decodeField = nil
+	}
+
+	found := false
+	err = c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
+		if stmt.ColumnType(0) == sqlite.SQLITE_NULL {
+			return nil
+		}
+		found = true
+		return c.scanColumn(stmt, 0, decodeField, destVal.Elem())
+	}, args...)
+	if err != nil {
+		return false, errors.WithMessage(err, fmt.Sprintf("For model %s, field %s", ms.ModelType, sf.Name))
+	}
+	return found, nil
+}
+
+// GroupCount counts records of model matching cond for each distinct value
+// of column. Keys are decoded like the field is, so for example grouping on
+// an int64 field gives int64 keys. NULL values are counted under a nil key.
+// Fields whose type can't be a map key, like []byte, are refused.
+func (c *Context) GroupCount(conn *sqlite.Conn, model interface{}, column string, cond builder.Cond) (map[interface{}]int64, error) {
+	ms := c.NewScope(model).GetModelStruct()
+	sf, err := ms.columnField(column)
+	if err != nil {
+		return nil, err
+	}
+
+	keyTyp := sf.Struct.Type
+	for keyTyp.Kind() == reflect.Ptr {
+		keyTyp = keyTyp.Elem()
+	}
+	if !keyTyp.Comparable() {
+		return nil, errors.Errorf("GroupCount can't group %v by field %s, since %v values can't be map keys", ms.ModelType, sf.Name, keyTyp)
+	}
+
+	qualified := fmt.Sprintf("%s.%s", EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName))
+	b := builder.Select(qualified, "count(*)").From(ms.TableName).Where(cond)
+	query, args, err := Search{}.GroupBy(qualified).ToSQL(b)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[interface{}]int64)
+	err = c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
+		count := stmt.ColumnInt64(1)
+		if stmt.ColumnType(0) == sqlite.SQLITE_NULL {
+			result[nil] += count
+			return nil
+		}
+
+		key := reflect.New(keyTyp).Elem()
+		err := c.scanColumn(stmt, 0, sf, key)
+		if err != nil {
+			return err
+		}
+		// some distinct stored values may decode to the same key
+		result[key.Interface()] += count
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("For model %s, field %s", ms.ModelType, sf.Name))
+	}
+	return result, nil
+}
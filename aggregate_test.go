@@ -0,0 +1,110 @@
+package hades_test
+
+import (
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Aggregate(t *testing.T) {
+	type User struct {
+		ID   int64
+		Name string
+	}
+
+	type Game struct {
+		ID          int64
+		Title       string
+		UserID      int64
+		Price       int64
+		Kind        *string
+		PublishedAt *time.Time
+	}
+
+	models := []interface{}{&User{}, &Game{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		early := time.Date(2017, time.May, 1, 10, 0, 0, 0, time.UTC)
+		late := time.Date(2018, time.March, 14, 15, 9, 26, 0, time.UTC)
+		tool := "tool"
+
+		wtest.Must(t, c.Save(conn, []*User{
+			&User{ID: 1, Name: "leafo"},
+			&User{ID: 2, Name: "fasterthanlime"},
+		}))
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 24, Title: "X-Moon", UserID: 1, Price: 500, PublishedAt: &late},
+			&Game{ID: 46, Title: "butler", UserID: 2, Price: 0, Kind: &tool},
+			&Game{ID: 48, Title: "itch", UserID: 1, Price: 250, Kind: &tool, PublishedAt: &early},
+		}))
+
+		var sum int64
+		ok, err := c.Sum(conn, &Game{}, "Price", &sum, builder.Eq{"user_id": 1}, hades.Search{})
+		wtest.Must(t, err)
+		assert.True(t, ok)
+		assert.EqualValues(t, 750, sum)
+
+		var avg float64
+		ok, err = c.Avg(conn, &Game{}, "price", &avg, builder.NewCond(), hades.Search{})
+		wtest.Must(t, err)
+		assert.True(t, ok)
+		assert.InDelta(t, 250.0, avg, 0.001)
+
+		var latest time.Time
+		ok, err = c.Max(conn, &Game{}, "PublishedAt", &latest, builder.NewCond(), hades.Search{})
+		wtest.Must(t, err)
+		assert.True(t, ok)
+		assert.True(t, late.Equal(latest))
+
+		var earliest *time.Time
+		ok, err = c.Min(conn, &Game{}, "PublishedAt", &earliest, builder.NewCond(), hades.Search{})
+		wtest.Must(t, err)
+		assert.True(t, ok)
+		assert.True(t, early.Equal(*earliest))
+
+		var minPrice int64 = -1
+		ok, err = c.Min(conn, &Game{}, "Price", &minPrice,
+			builder.Eq{"users.name": "leafo"},
+			hades.Search{}.Join("users", "users.id = games.user_id"))
+		wtest.Must(t, err)
+		assert.True(t, ok)
+		assert.EqualValues(t, 250, minPrice)
+
+		sum = -1
+		ok, err = c.Sum(conn, &Game{}, "Price", &sum, builder.Eq{"user_id": 1000}, hades.Search{})
+		wtest.Must(t, err)
+		assert.False(t, ok, "empty sets have no sum")
+		assert.EqualValues(t, -1, sum, "dest must be left untouched for empty sets")
+
+		_, err = c.Max(conn, &Game{}, "Developer", &sum, builder.NewCond(), hades.Search{})
+		assert.Error(t, err, "must refuse unknown columns")
+
+		_, err = c.Max(conn, &Game{}, "Price", sum, builder.NewCond(), hades.Search{})
+		assert.Error(t, err, "must refuse non-pointer dest")
+
+		byUser, err := c.GroupCount(conn, &Game{}, "UserID", builder.NewCond())
+		wtest.Must(t, err)
+		assert.EqualValues(t, map[interface{}]int64{
+			int64(1): 2,
+			int64(2): 1,
+		}, byUser)
+
+		byKind, err := c.GroupCount(conn, &Game{}, "Kind", builder.Gt{"price": 0})
+		wtest.Must(t, err)
+		assert.EqualValues(t, map[interface{}]int64{
+			nil:    1,
+			"tool": 1,
+		}, byKind)
+
+		count, err := c.Count(conn, &Game{},
+			builder.Eq{"users.name": "leafo"},
+			hades.Search{}.Join("users", "users.id = games.user_id"))
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count)
+	})
+}
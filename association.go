@@ -0,0 +1,109 @@
+package hades
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// association describes how records of a model relate to
+// the records of one of its associations.
+type association struct {
+	name         string
+	ms           *ModelStruct
+	assoc        *ModelStruct
+	relationship *Relationship
+	// only set for many_to_many
+	joinTable string
+}
+
+// association looks up one of the associations of ms by field name.
+// The associated model must be known to c.
+func (c *Context) association(ms *ModelStruct, name string) (*association, error) {
+	return findAssociation(ms, name, func(typ reflect.Type) (*ModelStruct, error) {
+		scope, err := c.scopeByType(typ)
+		if err != nil {
+			return nil, err
+		}
+		return scope.GetModelStruct(), nil
+	})
+}
+
+// findAssociation looks up one of the associations of ms by field name,
+// using lookup to find the model struct of the associated model.
+func findAssociation(ms *ModelStruct, name string, lookup func(typ reflect.Type) (*ModelStruct, error)) (*association, error) {
+	sf, ok := ms.StructFieldsByName[name]
+	if !ok || sf.Relationship == nil {
+		return nil, errors.Errorf("%v has no association named %s", ms.ModelType, name)
+	}
+
+	typ := sf.Struct.Type
+	for typ.Kind() == reflect.Slice || typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	assocMS, err := lookup(typ)
+	if err != nil {
+		return nil, errors.WithMessage(err, fmt.Sprintf("for association %s of %v", name, ms.ModelType))
+	}
+
+	a := &association{
+		name:         name,
+		ms:           ms,
+		assoc:        assocMS,
+		relationship: sf.Relationship,
+	}
+
+	switch sf.Relationship.Kind {
+	case "has_many", "has_one", "belongs_to":
+		if len(sf.Relationship.ForeignDBNames) == 0 || len(sf.Relationship.ForeignDBNames) != len(sf.Relationship.AssociationForeignDBNames) {
+			return nil, errors.Errorf("association %s of %v has no usable foreign keys", name, ms.ModelType)
+		}
+	case "many_to_many":
+		jth := sf.Relationship.JoinTableHandler
+		if jth == nil || len(jth.SourceForeignKeys()) == 0 || len(jth.DestinationForeignKeys()) == 0 {
+			return nil, errors.Errorf("association %s of %v has no usable join table", name, ms.ModelType)
+		}
+		a.joinTable = jth.Table()
+	default:
+		return nil, errors.Errorf("association %s of %v is a %s relationship, which isn't supported", name, ms.ModelType, sf.Relationship.Kind)
+	}
+	return a, nil
+}
+
+// links returns the conditions relating records of the model (in table
+// from) to associated records (in table to), as "x.a = y.b" expressions.
+// For many_to_many associations, links returns the conditions between
+// from and the join table (in table join), then the conditions between
+// the join table and to.
+func (a *association) links(from, join, to string) (fromLinks []string, toLinks []string) {
+	column := func(table, name string) string {
+		return fmt.Sprintf("%s.%s", EscapeIdentifier(table), EscapeIdentifier(name))
+	}
+	eq := func(left, right string) string {
+		return fmt.Sprintf("%s = %s", left, right)
+	}
+
+	rel := a.relationship
+	switch rel.Kind {
+	case "has_many", "has_one":
+		// associated records have our key
+		for i, fk := range rel.ForeignDBNames {
+			fromLinks = append(fromLinks, eq(column(to, fk), column(from, rel.AssociationForeignDBNames[i])))
+		}
+	case "belongs_to":
+		// we have the key of the associated record
+		for i, fk := range rel.ForeignDBNames {
+			fromLinks = append(fromLinks, eq(column(from, fk), column(to, rel.AssociationForeignDBNames[i])))
+		}
+	case "many_to_many":
+		jth := rel.JoinTableHandler
+		for _, fk := range jth.SourceForeignKeys() {
+			fromLinks = append(fromLinks, eq(column(join, fk.DBName), column(from, fk.AssociationDBName)))
+		}
+		for _, fk := range jth.DestinationForeignKeys() {
+			toLinks = append(toLinks, eq(column(join, fk.DBName), column(to, fk.AssociationDBName)))
+		}
+	}
+	return
+}
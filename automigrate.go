@@ -2,7 +2,6 @@ package hades
 
 import (
 	"fmt"
-	"reflect"
 	"strings"
 	"time"
 
@@ -175,29 +174,8 @@ func (c *Context) createTable(conn *sqlite.Conn, ms *ModelStruct) error {
 			return nil
 		}
 
-		var sqliteType string
-		typ := sf.Struct.Type
-		if typ.Kind() == reflect.Ptr {
-			typ = typ.Elem()
-		}
-
-		switch typ.Kind() {
-		case reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8, reflect.Int,
-			reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8, reflect.Uint:
-			sqliteType = "INTEGER"
-		case reflect.Bool:
-			sqliteType = "BOOLEAN"
-		case reflect.Float64, reflect.Float32:
-			sqliteType = "REAL"
-		case reflect.String:
-			sqliteType = "TEXT"
-		case reflect.Struct:
-			if typ == reflect.TypeOf(time.Time{}) {
-				sqliteType = "DATETIME"
-				break
-			}
-			fallthrough
-		default:
+		sqliteType, err := c.sqliteType(sf.Struct.Type)
+		if err != nil {
 			return errors.Errorf("Unsupported model field type: %v (in model %v)", sf.Struct.Type, ms.ModelType)
 		}
 		modifier := ""
@@ -0,0 +1,182 @@
+package hades
+
+import (
+	"reflect"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// Change is a column of a record whose value differs from the
+// one it had when the record was loaded.
+type Change struct {
+	Field  string
+	Column string
+	// Old and New are values as stored in the database. Old is nil
+	// if the column wasn't loaded or saved yet.
+	Old interface{}
+	New interface{}
+}
+
+// Changes returns the columns of rec, a *Model, that changed since it
+// was loaded (or last saved) by c, in field order. It's an error for rec
+// not to have been loaded while TrackChanges was enabled.
+func (c *Context) Changes(rec interface{}) ([]Change, error) {
+	val := reflect.ValueOf(rec)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return nil, errors.Errorf("Changes expects a *Model, but got a %v", val.Type())
+	}
+	scope, err := c.scopeByType(val.Type())
+	if err != nil {
+		return nil, err
+	}
+
+	changes, tracked := c.changes(scope, val, nil)
+	if !tracked {
+		return nil, errors.Errorf("%v wasn't loaded with TrackChanges enabled", val.Type())
+	}
+	return changes, nil
+}
+
+// columnValue is the value of a column of a record,
+// as it would be stored in the database.
+type columnValue struct {
+	sf    *StructField
+	value interface{}
+}
+
+// columnValues returns the values of the given columns of rec,
+// a *Model, or of all of them if columns is nil.
+func (c *Context) columnValues(scope *Scope, rec reflect.Value, columns map[string]bool) []columnValue {
+	var values []columnValue
+
+	var processField func(sf *StructField, val reflect.Value)
+	processField = func(sf *StructField, val reflect.Value) {
+		field := val.FieldByName(sf.Name)
+		if sf.IsSquashed {
+			for _, nsf := range sf.SquashedFields {
+				processField(nsf, field)
+			}
+		}
+
+		if !sf.IsNormal {
+			return
+		}
+		if columns != nil && !columns[sf.DBName] {
+			return
+		}
+
+		value := c.encodeValue(sf, field.Interface())
+		if b, ok := value.([]byte); ok {
+			// the snapshot must not share memory with the record
+			value = append([]byte(nil), b...)
+		}
+		values = append(values, columnValue{sf: sf, value: value})
+	}
+
+	for _, sf := range scope.GetModelStruct().StructFields {
+		processField(sf, rec.Elem())
+	}
+	return values
+}
+
+// snapshot records the current values of the given columns of rec
+// (or all of them if columns is nil), as the values stored in the
+// database. If merge is false, previously recorded values are dropped.
+func (c *Context) snapshot(rec reflect.Value, columns map[string]bool, merge bool) {
+	scope := c.ScopeMap.ByType(rec.Type())
+	if scope == nil || c.records == nil {
+		return
+	}
+
+	state := &recordState{
+		snapshot: make(map[string]interface{}),
+	}
+	if old := c.records.get(rec); old != nil {
+		state.partial = old.partial
+		if merge {
+			for column, value := range old.snapshot {
+				state.snapshot[column] = value
+			}
+		}
+	}
+
+	for _, cv := range c.columnValues(scope, rec, columns) {
+		state.snapshot[cv.sf.DBName] = cv.value
+	}
+	c.records.set(rec, state)
+}
+
+// changes returns the columns of rec that differ from its snapshot,
+// restricted to columns if it isn't nil, and whether rec has a snapshot
+// at all. Columns missing from the snapshot count as changed, unless
+// they weren't loaded in the first place.
+func (c *Context) changes(scope *Scope, rec reflect.Value, columns map[string]bool) ([]Change, bool) {
+	if c.records.len() == 0 {
+		return nil, false
+	}
+	state := c.records.get(rec)
+	if state == nil || state.snapshot == nil {
+		return nil, false
+	}
+
+	var changes []Change
+	for _, cv := range c.columnValues(scope, rec, intersectColumns(columns, state.partial)) {
+		old, ok := state.snapshot[cv.sf.DBName]
+		if ok && reflect.DeepEqual(old, cv.value) {
+			continue
+		}
+		changes = append(changes, Change{
+			Field:  cv.sf.Name,
+			Column: cv.sf.DBName,
+			Old:    old,
+			New:    cv.value,
+		})
+	}
+	return changes, true
+}
+
+// saveChanges saves rec by only updating the columns that changed since
+// it was loaded, if it's being tracked, and returns whether it was updated
+// or skipped. It returns 0 if rec must be saved in full instead: because
+// it's not tracked, because its primary key changed, or because it was
+// deleted since it was loaded.
+func (c *Context) saveChanges(conn *sqlite.Conn, scope *Scope, rec reflect.Value, columns map[string]bool) (RowOutcome, error) {
+	changes, tracked := c.changes(scope, rec, columns)
+	if !tracked {
+		return 0, nil
+	}
+	if len(changes) == 0 {
+		return RowSkipped, nil
+	}
+
+	ms := scope.GetModelStruct()
+	sets := make(builder.Eq)
+	changed := make(map[string]bool)
+	for _, change := range changes {
+		for _, pf := range ms.PrimaryFields {
+			if pf.DBName == change.Column {
+				return 0, nil
+			}
+		}
+		sets[EscapeIdentifier(change.Column)] = change.New
+		changed[change.Column] = true
+	}
+
+	where := make(builder.Eq)
+	for _, pf := range ms.PrimaryFields {
+		where[EscapeIdentifier(pf.DBName)] = c.encodeValue(pf, rec.Elem().FieldByName(pf.Name).Interface())
+	}
+
+	err := c.Exec(conn, builder.Update(sets).Where(where).Into(ms.TableName), nil)
+	if err != nil {
+		return 0, err
+	}
+	if conn.Changes() == 0 {
+		// the record was deleted since it was loaded
+		return 0, nil
+	}
+	c.snapshot(rec, changed, true)
+	return RowUpdated, nil
+}
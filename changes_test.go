@@ -0,0 +1,139 @@
+package hades_test
+
+import (
+	"strings"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TrackChanges(t *testing.T) {
+	type Upload struct {
+		ID       int64
+		GameID   int64
+		Filename string
+	}
+
+	type Game struct {
+		ID          int64
+		Title       string
+		Description string
+		Uploads     []*Upload
+	}
+
+	models := []interface{}{
+		&Game{},
+		&Upload{},
+	}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		var statements []string
+		consumer := c.Consumer
+		c.Consumer = &state.Consumer{
+			OnMessage: func(lvl string, msg string) {
+				if strings.Contains(msg, "INSERT INTO") || strings.Contains(msg, "UPDATE ") {
+					statements = append(statements, msg)
+				}
+				consumer.OnMessage(lvl, msg)
+			},
+		}
+
+		wtest.Must(t, c.Save(conn, []*Game{
+			{ID: 1, Title: "First", Description: "One", Uploads: []*Upload{{ID: 10, Filename: "a.zip"}}},
+			{ID: 2, Title: "Second", Description: "Two"},
+			{ID: 3, Title: "Third", Description: "Three"},
+		}, hades.Assoc("Uploads")))
+
+		untracked := &Game{}
+		wtest.Must(t, c.Get(conn, untracked, int64(1)))
+		_, err := c.Changes(untracked)
+		assert.Error(t, err)
+
+		c.TrackChanges = true
+
+		var games []*Game
+		wtest.Must(t, c.Select(conn, &games, builder.NewCond(), hades.Search{}.OrderBy("id ASC")))
+		wtest.Must(t, c.Preload(conn, games, hades.Assoc("Uploads")))
+		assert.Len(t, games, 3)
+
+		changes, err := c.Changes(games[0])
+		wtest.Must(t, err)
+		assert.Empty(t, changes)
+
+		games[0].Title = "First, renamed"
+		games[0].Uploads[0].Filename = "b.zip"
+		changes, err = c.Changes(games[0])
+		wtest.Must(t, err)
+		assert.EqualValues(t, []hades.Change{
+			{Field: "Title", Column: "title", Old: "First", New: "First, renamed"},
+		}, changes)
+
+		statements = nil
+		wtest.Must(t, c.Save(conn, games, hades.Assoc("Uploads")))
+		assert.Len(t, statements, 2)
+		assert.Contains(t, statements[0]+statements[1], "UPDATE games SET title=? WHERE id=?")
+		assert.Contains(t, statements[0]+statements[1], "UPDATE uploads SET filename=? WHERE id=?")
+
+		changes, err = c.Changes(games[0])
+		wtest.Must(t, err)
+		assert.Empty(t, changes)
+
+		// nothing changed since the last save
+		statements = nil
+		wtest.Must(t, c.Save(conn, games, hades.Assoc("Uploads")))
+		assert.Empty(t, statements)
+
+		g := &Game{}
+		wtest.Must(t, c.Get(conn, g, int64(1)))
+		assert.EqualValues(t, "First, renamed", g.Title)
+		assert.EqualValues(t, "One", g.Description)
+
+		// only loaded columns are compared
+		var partial []*Game
+		wtest.Must(t, c.Select(conn, &partial, builder.Eq{"id": 2}, hades.Search{}.Fields("Title")))
+		partial[0].Title = "Second, renamed"
+		changes, err = c.Changes(partial[0])
+		wtest.Must(t, err)
+		assert.Len(t, changes, 1)
+
+		statements = nil
+		wtest.Must(t, c.Save(conn, partial))
+		assert.Len(t, statements, 1)
+		wtest.Must(t, c.Get(conn, g, int64(2)))
+		assert.EqualValues(t, "Second, renamed", g.Title)
+		assert.EqualValues(t, "Two", g.Description)
+
+		// new records are inserted, then tracked
+		fresh := &Game{ID: 4, Title: "Fourth"}
+		statements = nil
+		wtest.Must(t, c.Save(conn, fresh))
+		assert.Len(t, statements, 1)
+		assert.Contains(t, statements[0], "INSERT INTO")
+
+		fresh.Description = "Four"
+		statements = nil
+		wtest.Must(t, c.Save(conn, fresh))
+		assert.Len(t, statements, 1)
+		assert.Contains(t, statements[0], "UPDATE games SET description=? WHERE id=?")
+
+		// records deleted since they were loaded are inserted again
+		deleted := &Game{}
+		wtest.Must(t, c.Get(conn, deleted, int64(3)))
+		_, err = c.Delete(conn, &Game{}, builder.Eq{"id": 3})
+		wtest.Must(t, err)
+		deleted.Title = "Third, restored"
+		wtest.Must(t, c.Save(conn, deleted))
+
+		count, err := c.Count(conn, &Game{}, builder.Eq{"id": 3})
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, count)
+		wtest.Must(t, c.Get(conn, g, int64(3)))
+		assert.EqualValues(t, "Third, restored", g.Title)
+		assert.EqualValues(t, "Three", g.Description)
+	})
+}
@@ -0,0 +1,202 @@
+package hades
+
+import (
+	"reflect"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/pkg/errors"
+)
+
+// Codec describes how to store values of a type hades doesn't
+// know about natively, for example:
+//
+//   c.RegisterCodec(reflect.TypeOf(time.Duration(0)), hades.Codec{
+//     SQLiteType: "INTEGER",
+//     Encode: func(v interface{}) interface{} {
+//       return int64(v.(time.Duration) / time.Millisecond)
+//     },
+//     Decode: func(stmt *sqlite.Stmt, col int) (interface{}, error) {
+//       return time.Duration(stmt.ColumnInt64(col)) * time.Millisecond, nil
+//     },
+//   })
+//
+type Codec struct {
+	// SQLiteType is the column type used when creating tables
+	SQLiteType string
+	// Encode converts a value of the registered type into something
+	// sqlite can bind: an integer, a float, a string, a []byte or nil.
+	Encode func(v interface{}) interface{}
+	// Decode reads column col of stmt and returns a value of the
+	// registered type. It's never called for NULL columns scanned
+	// into pointer fields.
+	Decode func(stmt *sqlite.Stmt, col int) (interface{}, error)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// RegisterCodec makes c store values of type typ using codec.
+// Codecs are consulted before the built-in conversions, so they
+// can also be used to override how hades stores a type.
+func (c *Context) RegisterCodec(typ reflect.Type, codec Codec) {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if c.codecs == nil {
+		c.codecs = make(map[reflect.Type]*Codec)
+	}
+	c.codecs[typ] = &codec
+
+	// whether a field is a column or not might have changed,
+	// so model structs need to be computed again.
+	c.modelStructs = newModelStructsMap()
+	c.ScopeMap.Each(func(scope *Scope) error {
+		scope.fields = nil
+		return nil
+	})
+}
+
+func (c *Context) lookupCodec(typ reflect.Type) *Codec {
+	if c == nil || c.codecs == nil {
+		return nil
+	}
+	return c.codecs[typ]
+}
+
+// sqliteType returns the column type used to store fields of type typ
+func (c *Context) sqliteType(typ reflect.Type) (string, error) {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if codec := c.lookupCodec(typ); codec != nil {
+		return codec.SQLiteType, nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8, reflect.Int,
+		reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8, reflect.Uint:
+		return "INTEGER", nil
+	case reflect.Bool:
+		return "BOOLEAN", nil
+	case reflect.Float64, reflect.Float32:
+		return "REAL", nil
+	case reflect.String:
+		return "TEXT", nil
+	case reflect.Struct:
+		if typ == timeType {
+			return "DATETIME", nil
+		}
+	}
+	return "", errors.Errorf("unsupported type %v", typ)
+}
+
+// encodeValue converts x into a value sqlite can bind. sf is used
+// for per-field settings, and may be nil.
+func (c *Context) encodeValue(sf *StructField, x interface{}) interface{} {
+	if x == nil {
+		return nil
+	}
+
+	typ := reflect.TypeOf(x)
+	value := reflect.ValueOf(x)
+	wasPtr := false
+
+	if typ.Kind() == reflect.Ptr {
+		if value.IsNil() {
+			return nil
+		}
+
+		wasPtr = true
+		typ = typ.Elem()
+		value = value.Elem()
+	}
+
+	if codec := c.lookupCodec(typ); codec != nil {
+		return codec.Encode(value.Interface())
+	}
+
+	switch typ.Kind() {
+	case reflect.Bool:
+		if value.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Struct:
+		if typ == timeType {
+			return c.encodeTime(value.Interface().(time.Time), c.timeFormat(sf))
+		}
+	}
+
+	if wasPtr {
+		return value.Interface()
+	}
+	return x
+}
+
+// decodeColumn reads column col of stmt into dst, which must be settable.
+// NULL columns are decoded as nil into pointers. sf is used for per-field
+// settings, and may be nil.
+func (c *Context) decodeColumn(stmt *sqlite.Stmt, col int, sf *StructField, dst reflect.Value) error {
+	typ := dst.Type()
+
+	if typ.Kind() == reflect.Ptr {
+		if stmt.ColumnType(col) == sqlite.SQLITE_NULL {
+			dst.Set(reflect.Zero(typ))
+			return nil
+		}
+
+		el := reflect.New(typ.Elem())
+		err := c.decodeColumn(stmt, col, sf, el.Elem())
+		if err != nil {
+			return err
+		}
+		dst.Set(el)
+		return nil
+	}
+
+	if codec := c.lookupCodec(typ); codec != nil {
+		v, err := codec.Decode(stmt, col)
+		if err != nil {
+			return errors.WithMessage(err, "decoding with codec")
+		}
+
+		val := reflect.ValueOf(v)
+		if !val.IsValid() {
+			dst.Set(reflect.Zero(typ))
+			return nil
+		}
+		if !val.Type().ConvertibleTo(typ) {
+			return errors.Errorf("codec for %v decoded a value of type %v", typ, val.Type())
+		}
+		dst.Set(val.Convert(typ))
+		return nil
+	}
+
+	switch typ.Kind() {
+	case reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8, reflect.Int:
+		dst.SetInt(stmt.ColumnInt64(col))
+	case reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8, reflect.Uint:
+		dst.SetUint(uint64(stmt.ColumnInt64(col)))
+	case reflect.Float64, reflect.Float32:
+		dst.SetFloat(stmt.ColumnFloat(col))
+	case reflect.Bool:
+		dst.SetBool(stmt.ColumnInt(col) == 1)
+	case reflect.String:
+		dst.SetString(stmt.ColumnText(col))
+	case reflect.Struct:
+		if typ == timeType {
+			tim, err := c.decodeTime(stmt, col, c.timeFormat(sf))
+			if err != nil {
+				return err
+			}
+			dst.Set(reflect.ValueOf(tim))
+			return nil
+		}
+		fallthrough
+	default:
+		return errors.Errorf("unknown kind %s", typ.Kind())
+	}
+	return nil
+}
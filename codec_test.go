@@ -0,0 +1,114 @@
+package hades_test
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Codecs(t *testing.T) {
+	type Session struct {
+		ID       int64
+		Duration time.Duration
+		Timeout  *time.Duration
+		Address  net.IP
+	}
+
+	dbpool, err := sqlite.Open("file:memory:?mode=memory", 0, 10)
+	wtest.Must(t, err)
+	defer dbpool.Close()
+
+	conn := dbpool.Get(context.Background().Done())
+	defer dbpool.Put(conn)
+
+	c, err := hades.NewContext(makeConsumer(t), &Session{})
+	wtest.Must(t, err)
+	c.Log = true
+
+	c.RegisterCodec(reflect.TypeOf(time.Duration(0)), hades.Codec{
+		SQLiteType: "INTEGER",
+		Encode: func(v interface{}) interface{} {
+			return int64(v.(time.Duration) / time.Millisecond)
+		},
+		Decode: func(stmt *sqlite.Stmt, col int) (interface{}, error) {
+			return time.Duration(stmt.ColumnInt64(col)) * time.Millisecond, nil
+		},
+	})
+	c.RegisterCodec(reflect.TypeOf(net.IP{}), hades.Codec{
+		SQLiteType: "TEXT",
+		Encode: func(v interface{}) interface{} {
+			return v.(net.IP).String()
+		},
+		Decode: func(stmt *sqlite.Stmt, col int) (interface{}, error) {
+			return net.ParseIP(stmt.ColumnText(col)), nil
+		},
+	})
+
+	wtest.Must(t, c.AutoMigrate(conn))
+	defer c.ExecRaw(conn, "DROP TABLE sessions", nil)
+
+	pti, err := c.PragmaTableInfo(conn, "sessions")
+	wtest.Must(t, err)
+	assert.EqualValues(t, 4, len(pti))
+	assert.EqualValues(t, "duration", pti[1].Name)
+	assert.EqualValues(t, "INTEGER", pti[1].Type)
+	assert.EqualValues(t, "timeout", pti[2].Name)
+	assert.EqualValues(t, "INTEGER", pti[2].Type)
+	assert.EqualValues(t, "address", pti[3].Name)
+	assert.EqualValues(t, "TEXT", pti[3].Type)
+
+	assert.EqualValues(t, 1500, c.DBValue(1500*time.Millisecond))
+	assert.EqualValues(t, "10.0.0.1", c.DBValue(net.ParseIP("10.0.0.1")))
+	assert.EqualValues(t, 1500*time.Millisecond, hades.DBValue(1500*time.Millisecond), "package-level DBValue only knows built-in types")
+
+	s := &Session{
+		ID:       1,
+		Duration: 2 * time.Second,
+		Address:  net.ParseIP("192.168.1.12"),
+	}
+	wtest.Must(t, c.Save(conn, s))
+
+	var rawDuration int64
+	var rawAddress string
+	wtest.Must(t, c.ExecRaw(conn, "SELECT duration, address FROM sessions WHERE id = 1", func(stmt *sqlite.Stmt) error {
+		rawDuration = stmt.ColumnInt64(0)
+		rawAddress = stmt.ColumnText(1)
+		return nil
+	}))
+	assert.EqualValues(t, 2000, rawDuration)
+	assert.EqualValues(t, "192.168.1.12", rawAddress)
+
+	ss := &Session{}
+	found, err := c.SelectOne(conn, ss, builder.Eq{"id": 1})
+	wtest.Must(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, 2*time.Second, ss.Duration)
+	assert.Nil(t, ss.Timeout)
+	assert.True(t, s.Address.Equal(ss.Address))
+
+	timeout := 30 * time.Second
+	s.Timeout = &timeout
+	s.Duration = 3 * time.Second
+	wtest.Must(t, c.Save(conn, s))
+
+	found, err = c.SelectOne(conn, ss, builder.Eq{"id": 1})
+	wtest.Must(t, err)
+	assert.True(t, found)
+	assert.EqualValues(t, 3*time.Second, ss.Duration)
+	assert.EqualValues(t, 30*time.Second, *ss.Timeout)
+
+	byDuration, err := c.GroupCount(conn, &Session{}, "Duration", builder.NewCond())
+	wtest.Must(t, err)
+	assert.EqualValues(t, map[interface{}]int64{3 * time.Second: 1}, byDuration)
+
+	_, err = c.GroupCount(conn, &Session{}, "Address", builder.NewCond())
+	assert.Error(t, err, "must refuse fields that can't be map keys")
+}
@@ -0,0 +1,312 @@
+package hades
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/pkg/errors"
+)
+
+// ConflictMode is what Save and Insert do with records that conflict with
+// existing ones, for example because they have the same primary key.
+type ConflictMode int
+
+const (
+	// OnConflictUpdate updates the existing record. It's what Save does
+	// by default.
+	OnConflictUpdate ConflictMode = iota + 1
+	// OnConflictIgnore leaves the existing record alone.
+	OnConflictIgnore
+	// OnConflictFail returns an error. It's what Insert does by default.
+	OnConflictFail
+	// OnConflictReplace deletes the existing record, then inserts the
+	// new one. Unlike OnConflictUpdate, columns that aren't saved are
+	// cleared, and SQLite resolves conflicts on every unique constraint.
+	OnConflictReplace
+)
+
+// RowOutcome is what Save or Insert did with a record, see Report.
+type RowOutcome int
+
+const (
+	RowInserted RowOutcome = iota + 1
+	RowUpdated
+	RowSkipped
+)
+
+func (o RowOutcome) String() string {
+	switch o {
+	case RowInserted:
+		return "inserted"
+	case RowUpdated:
+		return "updated"
+	case RowSkipped:
+		return "skipped"
+	}
+	return fmt.Sprintf("RowOutcome(%d)", int(o))
+}
+
+// SaveReport lists what Save or Insert did with each record passed to
+// them, in the order records were saved. If saving fails, it's incomplete.
+type SaveReport struct {
+	Rows []RowReport
+}
+
+// RowReport is what happened to a record, which is a *Model.
+type RowReport struct {
+	Record  interface{}
+	Outcome RowOutcome
+}
+
+// Outcome returns what happened to rec the last time it was saved,
+// or 0 if it's not part of the report.
+func (r *SaveReport) Outcome(rec interface{}) RowOutcome {
+	for i := len(r.Rows) - 1; i >= 0; i-- {
+		if r.Rows[i].Record == rec {
+			return r.Rows[i].Outcome
+		}
+	}
+	return 0
+}
+
+// conflict is how conflicts are handled when saving records of a
+// model. A nil *conflict is the default for Save: records that have
+// the same primary keys are updated, and nothing is reported.
+type conflict struct {
+	mode ConflictMode
+	// target lists the columns of the unique constraint
+	// conflicts are checked against
+	target         []string
+	explicitTarget bool
+	report         *SaveReport
+}
+
+// resolve turns the conflict handling specified by cp into a conflict
+// for records of ms, using defaultMode unless cp specifies one.
+func (cp *conflictParams) resolve(ms *ModelStruct, defaultMode ConflictMode) (*conflict, error) {
+	cf := &conflict{mode: defaultMode}
+	if cp != nil {
+		if cp.mode != 0 {
+			cf.mode = cp.mode
+		}
+		cf.report = cp.report
+
+		for _, name := range cp.target {
+			sf, err := ms.columnField(name)
+			if err != nil {
+				return nil, errors.WithMessage(err, "in OnConflict")
+			}
+			cf.target = append(cf.target, sf.DBName)
+			cf.explicitTarget = true
+		}
+	}
+	if !cf.explicitTarget {
+		for _, pf := range ms.PrimaryFields {
+			cf.target = append(cf.target, pf.DBName)
+		}
+	}
+	return cf, nil
+}
+
+func (cf *conflict) getMode() ConflictMode {
+	if cf == nil {
+		return OnConflictUpdate
+	}
+	return cf.mode
+}
+
+// updatesChanges returns whether tracked records can be saved by only
+// updating what changed: saveChanges finds records by primary key, so
+// it can't be used when conflicts are checked against another target.
+func (cf *conflict) updatesChanges() bool {
+	return cf.getMode() == OnConflictUpdate && (cf == nil || !cf.explicitTarget)
+}
+
+// sql returns how an INSERT statement for records of scope starts
+// ("INSERT" or "INSERT OR REPLACE"), and the clause that ends it,
+// if any. sets are the assignments for OnConflictUpdate.
+func (cf *conflict) sql(scope *Scope, sets []string) (string, string) {
+	var target []string
+	if cf != nil {
+		target = cf.target
+	} else {
+		for _, pf := range scope.GetModelStruct().PrimaryFields {
+			target = append(target, pf.DBName)
+		}
+	}
+
+	switch cf.getMode() {
+	case OnConflictIgnore:
+		return "INSERT", fmt.Sprintf("ON CONFLICT(%s) DO NOTHING", strings.Join(target, ","))
+	case OnConflictFail:
+		return "INSERT", ""
+	case OnConflictReplace:
+		return "INSERT OR REPLACE", ""
+	}
+
+	if len(sets) == 0 {
+		if cf == nil || !cf.explicitTarget {
+			return "INSERT", "ON CONFLICT DO NOTHING"
+		}
+		return "INSERT", fmt.Sprintf("ON CONFLICT(%s) DO NOTHING", strings.Join(target, ","))
+	}
+	return "INSERT", fmt.Sprintf("ON CONFLICT(%s) DO UPDATE SET %s",
+		strings.Join(target, ","),
+		strings.Join(sets, ","),
+	)
+}
+
+// checkColumns returns an error if saving only some columns of records
+// would lose data with cf's mode.
+func (cf *conflict) checkColumns(columns map[string]bool) error {
+	if columns != nil && cf.getMode() == OnConflictReplace {
+		return errors.Errorf("OnConflictReplace would clear the columns that aren't saved, refusing to use it with Fields, Omit or Search.Fields")
+	}
+	return nil
+}
+
+// withTarget returns columns along with the columns of cf's target,
+// which must be saved for conflicts to be detected. Primary keys are
+// always saved, so columns is returned as-is without an explicit target.
+func (cf *conflict) withTarget(columns map[string]bool) map[string]bool {
+	if columns == nil || cf == nil || !cf.explicitTarget {
+		return columns
+	}
+	res := make(map[string]bool)
+	for column := range columns {
+		res[column] = true
+	}
+	for _, column := range cf.target {
+		res[column] = true
+	}
+	return res
+}
+
+// record adds the outcomes of recs to cf's report, if any.
+func (cf *conflict) record(recs []reflect.Value, outcomes []RowOutcome) {
+	if cf == nil || cf.report == nil {
+		return
+	}
+	for i, rec := range recs {
+		cf.report.Rows = append(cf.report.Rows, RowReport{
+			Record:  rec.Interface(),
+			Outcome: outcomes[i],
+		})
+	}
+}
+
+// plannedOutcomes returns what inserting recs in a single statement will
+// do with each of them, if cf has a report. It must be called right before
+// running the statement. hasSets is whether conflicting records are updated.
+func (c *Context) plannedOutcomes(conn *sqlite.Conn, scope *Scope, cf *conflict, recs []reflect.Value, hasSets bool) ([]RowOutcome, error) {
+	if cf == nil || cf.report == nil {
+		return nil, nil
+	}
+
+	outcomes := make([]RowOutcome, len(recs))
+	if cf.mode == OnConflictFail {
+		// if there's a conflict, nothing is inserted
+		for i := range outcomes {
+			outcomes[i] = RowInserted
+		}
+		return outcomes, nil
+	}
+
+	conflicted := RowSkipped
+	if cf.mode == OnConflictReplace || (cf.mode == OnConflictUpdate && hasSets) {
+		conflicted = RowUpdated
+	}
+
+	target := make(map[string]bool)
+	for _, column := range cf.target {
+		target[column] = true
+	}
+
+	keys := make([][]columnValue, len(recs))
+	for i, rec := range recs {
+		keys[i] = c.columnValues(scope, rec, target)
+	}
+	existing, err := c.existingKeys(conn, scope.GetModelStruct(), keys)
+	if err != nil {
+		return nil, err
+	}
+
+	// records may conflict with earlier ones in the same statement
+	seen := make(map[string]bool)
+	for i, key := range keys {
+		hasNull := false
+		var values []interface{}
+		for _, cv := range key {
+			if cv.value == nil {
+				hasNull = true
+			}
+			values = append(values, cv.value)
+		}
+		if hasNull {
+			// NULLs never conflict in unique constraints
+			outcomes[i] = RowInserted
+			continue
+		}
+
+		id := fmt.Sprintf("%#v", values)
+		if existing[i] || seen[id] {
+			outcomes[i] = conflicted
+		} else {
+			outcomes[i] = RowInserted
+		}
+		seen[id] = true
+	}
+	return outcomes, nil
+}
+
+// existingKeys returns which of keys (values of the same columns of ms)
+// already exist in the table of ms, by index. Values are compared by
+// SQLite, so that they're converted like they would be on insert.
+func (c *Context) existingKeys(conn *sqlite.Conn, ms *ModelStruct, keys [][]columnValue) (map[int]bool, error) {
+	existing := make(map[int]bool)
+	if len(keys) == 0 || len(keys[0]) == 0 {
+		return existing, nil
+	}
+
+	var inputColumns, matches []string
+	for i, cv := range keys[0] {
+		input := fmt.Sprintf("k%d", i)
+		inputColumns = append(inputColumns, input)
+		matches = append(matches, fmt.Sprintf("%s.%s = input.%s", EscapeIdentifier(ms.TableName), EscapeIdentifier(cv.sf.DBName), input))
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(inputColumns)), ",")
+
+	pageSize := maxSqlVars / len(inputColumns)
+	for start := 0; start < len(keys); start += pageSize {
+		end := start + pageSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		var rows []string
+		var args []interface{}
+		for i := start; i < end; i++ {
+			rows = append(rows, fmt.Sprintf("(%d,%s)", i, placeholders))
+			for _, cv := range keys[i] {
+				args = append(args, cv.value)
+			}
+		}
+
+		query := fmt.Sprintf("WITH input(i,%s) AS (VALUES %s) SELECT input.i FROM input WHERE EXISTS (SELECT 1 FROM %s WHERE %s)",
+			strings.Join(inputColumns, ","),
+			strings.Join(rows, ","),
+			EscapeIdentifier(ms.TableName),
+			strings.Join(matches, " AND "),
+		)
+		err := c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
+			existing[stmt.ColumnInt(0)] = true
+			return nil
+		}, args...)
+		if err != nil {
+			return nil, errors.WithMessage(err, "checking for conflicts")
+		}
+	}
+	return existing, nil
+}
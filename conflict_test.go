@@ -0,0 +1,141 @@
+package hades_test
+
+import (
+	"reflect"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OnConflict(t *testing.T) {
+	type Game struct {
+		ID     int64
+		UserID int64
+		Slug   string
+		Title  string
+	}
+
+	models := []interface{}{&Game{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.ExecRaw(conn, "CREATE UNIQUE INDEX games_user_slug ON games (user_id, slug)", nil))
+
+		outcomes := func(report *hades.SaveReport) []hades.RowOutcome {
+			var res []hades.RowOutcome
+			for _, row := range report.Rows {
+				res = append(res, row.Outcome)
+			}
+			return res
+		}
+		title := func(id int64) string {
+			g := &Game{}
+			wtest.Must(t, c.Get(conn, g, id))
+			return g.Title
+		}
+		count := func() int64 {
+			n, err := c.Count(conn, &Game{}, builder.NewCond())
+			wtest.Must(t, err)
+			return n
+		}
+
+		g1 := &Game{ID: 1, UserID: 1, Slug: "overland", Title: "Overland"}
+		g2 := &Game{ID: 2, UserID: 1, Slug: "sauerbraten", Title: "Sauerbraten"}
+		report := &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, []*Game{g1, g2}, hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowInserted, hades.RowInserted}, outcomes(report))
+		assert.EqualValues(t, hades.RowInserted, report.Outcome(g2))
+
+		// default mode updates
+		g1.Title = "Overland, updated"
+		g3 := &Game{ID: 3, UserID: 2, Slug: "overland", Title: "Another Overland"}
+		report = &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, []*Game{g1, g3}, hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowUpdated, hades.RowInserted}, outcomes(report))
+		assert.EqualValues(t, "Overland, updated", title(1))
+
+		// conflicts within the same save are reported too
+		report = &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, []*Game{
+			{ID: 4, UserID: 3, Slug: "a", Title: "First"},
+			{ID: 4, UserID: 3, Slug: "a", Title: "Second"},
+		}, hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowInserted, hades.RowUpdated}, outcomes(report))
+		assert.EqualValues(t, "Second", title(4))
+
+		report = &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, []*Game{
+			{ID: 1, UserID: 1, Slug: "overland", Title: "Ignored"},
+			{ID: 5, UserID: 3, Slug: "b", Title: "New"},
+		}, hades.OnConflict(hades.OnConflictIgnore), hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowSkipped, hades.RowInserted}, outcomes(report))
+		assert.EqualValues(t, "Overland, updated", title(1))
+		assert.EqualValues(t, 5, count())
+
+		err := c.Save(conn, &Game{ID: 1, UserID: 1, Slug: "overland", Title: "Failed"}, hades.OnConflict(hades.OnConflictFail))
+		assert.Error(t, err, "must fail on duplicates")
+		assert.EqualValues(t, "Overland, updated", title(1))
+
+		report = &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, &Game{ID: 2, UserID: 1, Slug: "sauerbraten", Title: "Replaced"},
+			hades.OnConflict(hades.OnConflictReplace), hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowUpdated}, outcomes(report))
+		assert.EqualValues(t, "Replaced", title(2))
+
+		// conflicts on a unique index instead of the primary key
+		report = &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, []*Game{
+			{ID: 100, UserID: 1, Slug: "overland", Title: "By slug"},
+			{ID: 101, UserID: 1, Slug: "new-slug", Title: "New slug"},
+		}, hades.OnConflict(hades.OnConflictUpdate, "UserID", "slug"), hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowUpdated, hades.RowInserted}, outcomes(report))
+		assert.EqualValues(t, "By slug", title(1))
+		assert.EqualValues(t, 6, count())
+
+		// targets are saved even if Fields doesn't list them
+		report = &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, &Game{ID: 103, UserID: 1, Slug: "overland", Title: "By slug, again"},
+			hades.OnConflict(hades.OnConflictUpdate, "UserID", "Slug"), hades.Fields("Title"), hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowUpdated}, outcomes(report))
+		assert.EqualValues(t, "By slug, again", title(1))
+		assert.EqualValues(t, 6, count())
+
+		report = &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, &Game{ID: 102, UserID: 1, Slug: "overland", Title: "Ignored"},
+			hades.OnConflict(hades.OnConflictIgnore, "UserID", "Slug"), hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowSkipped}, outcomes(report))
+		assert.EqualValues(t, 6, count())
+
+		// tracked records must still be matched by target, not by primary key
+		c.TrackChanges = true
+		tracked := &Game{}
+		wtest.Must(t, c.Get(conn, tracked, 3))
+		tracked.UserID = 1
+		tracked.Slug = "overland"
+		tracked.Title = "Tracked by slug"
+		report = &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, []*Game{tracked}, hades.OnConflict(hades.OnConflictUpdate, "UserID", "Slug"), hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowUpdated}, outcomes(report))
+		assert.EqualValues(t, "Tracked by slug", title(1))
+		assert.EqualValues(t, "Another Overland", title(3))
+		c.TrackChanges = false
+
+		assert.Error(t, c.Save(conn, g1, hades.OnConflict(hades.OnConflictUpdate, "Title")), "must refuse targets without a unique index")
+		assert.Error(t, c.Save(conn, g1, hades.OnConflict(hades.OnConflictUpdate, "Nope")), "must refuse unknown fields")
+		assert.Error(t, c.Save(conn, g1, hades.OnConflict(hades.OnConflictReplace), hades.Fields("Title")), "must refuse to clear columns")
+
+		// Insert fails on conflicts by default
+		scope := c.ScopeMap.ByType(reflect.TypeOf(g1))
+		assert.Error(t, c.Insert(conn, scope, reflect.ValueOf(g1)))
+
+		report = &hades.SaveReport{}
+		wtest.Must(t, c.Insert(conn, scope, reflect.ValueOf(g1), hades.OnConflict(hades.OnConflictIgnore), hades.Report(report)))
+		g7 := &Game{ID: 7, UserID: 4, Slug: "c", Title: "Inserted"}
+		wtest.Must(t, c.Insert(conn, scope, reflect.ValueOf(g7), hades.Report(report)))
+		assert.EqualValues(t, []hades.RowOutcome{hades.RowSkipped, hades.RowInserted}, outcomes(report))
+		assert.EqualValues(t, "inserted", report.Outcome(g7).String())
+	})
+}
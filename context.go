@@ -1,6 +1,8 @@
 package hades
 
 import (
+	"reflect"
+
 	"github.com/itchio/wharf/state"
 )
 
@@ -9,6 +11,34 @@ type Context struct {
 	Consumer *state.Consumer
 	Error    error
 	Log      bool
+
+	// TimeFormat is how time.Time fields are stored, unless
+	// they specify otherwise with a `hades:"time:format"` tag.
+	// It defaults to TimeFormatRFC3339.
+	TimeFormat TimeFormat
+	// TimeUTC converts times to UTC before storing them.
+	TimeUTC bool
+	// StrictScan makes Scan check that column names match the fields
+	// being scanned into, and refuse values that would otherwise be
+	// silently coerced: NULL into non-pointer fields, mismatched storage
+	// classes, and integers that don't fit in their field.
+	StrictScan bool
+	// FullScanThreshold, when positive, makes ExecRaw check the query plan
+	// of each distinct query the first time it runs, and warn through
+	// Consumer when it reads the whole of a table that has at least that
+	// many rows. See also ExplainQueryPlan and RecordFullScans.
+	FullScanThreshold int64
+	// TrackChanges makes the context remember the values of records it
+	// loads, so that Save only updates the columns that changed since, and
+	// skips records that didn't change at all. See also Changes.
+	TrackChanges bool
+
+	codecs       map[reflect.Type]*Codec
+	modelStructs *safeModelStructsMap
+	// records and scans are only ever created by NewContext, since
+	// a Context may be used from several connections at once.
+	records *recordStates
+	scans   *scanChecker
 }
 
 func NewContext(consumer *state.Consumer, models ...interface{}) (*Context, error) {
@@ -16,8 +46,11 @@ func NewContext(consumer *state.Consumer, models ...interface{}) (*Context, erro
 		consumer = &state.Consumer{}
 	}
 	c := &Context{
-		Consumer: consumer,
-		ScopeMap: NewScopeMap(),
+		Consumer:     consumer,
+		ScopeMap:     NewScopeMap(),
+		modelStructs: newModelStructsMap(),
+		records:      newRecordStates(),
+		scans:        newScanChecker(),
 	}
 
 	for _, m := range models {
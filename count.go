@@ -5,23 +5,15 @@ import (
 	"github.com/go-xorm/builder"
 )
 
-func (c *Context) Count(conn *sqlite.Conn, model interface{}, cond builder.Cond) (int64, error) {
-	ms := c.NewScope(model).GetModelStruct()
-
-	query, args, err := builder.Select("count(*)").From(ms.TableName).Where(cond).ToSQL()
+// Count counts records of model matching cond. An optional search
+// can add joins or common table expressions. If it groups rows,
+// groups are counted instead.
+func (c *Context) Count(conn *sqlite.Conn, model interface{}, cond builder.Cond, search ...Search) (int64, error) {
+	s, err := optionalSearch("Count", search)
 	if err != nil {
 		return 0, err
 	}
 
-	var result int64
-
-	err = c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
-		result = stmt.ColumnInt64(0)
-		return nil
-	}, args...)
-
-	if err != nil {
-		return 0, err
-	}
-	return result, nil
+	ms := c.NewScope(model).GetModelStruct()
+	return c.countWithSearch(conn, ms, cond, s)
 }
@@ -1,39 +1,15 @@
 package hades
 
-import (
-	"reflect"
-	"time"
-)
-
+// DBValue converts x into a value sqlite can bind, using the
+// built-in conversions only. See (*Context).DBValue
 func DBValue(x interface{}) interface{} {
-	typ := reflect.TypeOf(x)
-	value := reflect.ValueOf(x)
-	wasPtr := false
-
-	if typ.Kind() == reflect.Ptr {
-		if value.IsNil() {
-			return nil
-		}
-
-		wasPtr = true
-		typ = typ.Elem()
-		value = value.Elem()
-	}
-
-	switch typ.Kind() {
-	case reflect.Bool:
-		if value.Bool() {
-			return 1
-		}
-		return 0
-	case reflect.Struct:
-		if typ == reflect.TypeOf(time.Time{}) {
-			return value.Interface().(time.Time).Format(time.RFC3339Nano)
-		}
-	}
+	var c *Context
+	return c.encodeValue(nil, x)
+}
 
-	if wasPtr {
-		return value.Interface()
-	}
-	return x
+// DBValue converts x into a value sqlite can bind, consulting
+// the codecs registered on c before falling back to the built-in
+// conversions.
+func (c *Context) DBValue(x interface{}) interface{} {
+	return c.encodeValue(nil, x)
 }
@@ -8,18 +8,34 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (c *Context) Delete(conn *sqlite.Conn, model interface{}, cond builder.Cond) error {
+// Delete deletes all records of model matching cond, and returns
+// how many were deleted. An optional search can only add common table
+// expressions, for use in cond, see Search.With.
+func (c *Context) Delete(conn *sqlite.Conn, model interface{}, cond builder.Cond, search ...Search) (int64, error) {
+	s, err := optionalSearch("Delete", search)
+	if err != nil {
+		return 0, err
+	}
+	err = s.checkOnlyCTEs("Delete")
+	if err != nil {
+		return 0, err
+	}
+
 	modelType := reflect.TypeOf(model)
 
 	scope := c.ScopeMap.ByType(modelType)
 	if scope == nil {
-		return errors.Errorf("%v is not a model known to this hades context", modelType)
+		return 0, errors.Errorf("%v is not a model known to this hades context", modelType)
 	}
 
 	if cond == builder.NewCond() {
-		return errors.Errorf("refusing to blindly delete all %v without an explicit builder.Expr(\"1\") clause", modelType)
+		return 0, errors.Errorf("refusing to blindly delete all %v without an explicit builder.Expr(\"1\") clause", modelType)
 	}
 
 	b := builder.Delete(cond).From(scope.TableName())
-	return c.Exec(conn, b, nil)
+	err = c.ExecWithSearch(conn, b, s, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int64(conn.Changes()), nil
 }
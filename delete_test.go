@@ -43,18 +43,20 @@ func Test_Delete(t *testing.T) {
 		wtest.Must(t, err)
 		assert.EqualValues(t, 3, count)
 
-		err = c.Delete(conn, &Story{}, builder.NewCond())
+		_, err = c.Delete(conn, &Story{}, builder.NewCond())
 		assert.Error(t, err, "must refuse to delete with empty cond")
 		assert.Contains(t, err.Error(), "refusing to blindly")
 
-		err = c.Delete(conn, &Story{}, builder.Eq{"id": 1})
+		deleted, err := c.Delete(conn, &Story{}, builder.Eq{"id": 1})
 		wtest.Must(t, err)
+		assert.EqualValues(t, 1, deleted)
 		count, err = c.Count(conn, &Story{}, builder.NewCond())
 		wtest.Must(t, err)
 		assert.EqualValues(t, 2, count)
 
-		err = c.Delete(conn, &Story{}, builder.Expr("1"))
+		deleted, err = c.Delete(conn, &Story{}, builder.Expr("1"))
 		assert.NoError(t, err, "must delete all with expr")
+		assert.EqualValues(t, 2, deleted)
 		count, err = c.Count(conn, &Story{}, builder.NewCond())
 		wtest.Must(t, err)
 		assert.EqualValues(t, 0, count)
@@ -4,4 +4,7 @@ import "github.com/pkg/errors"
 
 var (
 	ErrUnaddressable = errors.New("using unaddressable value")
+	// ErrStop can be returned from SelectEach and SelectBatches callbacks
+	// to stop iterating early without failing.
+	ErrStop = errors.New("stop iterating")
 )
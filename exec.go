@@ -20,12 +20,15 @@ func (c *Context) Exec(conn *sqlite.Conn, b *builder.Builder, resultFn ResultFn)
 }
 
 func (c *Context) ExecWithSearch(conn *sqlite.Conn, b *builder.Builder, search Search, resultFn ResultFn) error {
-	query, args, err := b.ToSQL()
+	search, err := search.resolveJoins(c)
 	if err != nil {
-		return errors.WithStack(err)
+		return err
 	}
 
-	query = search.Apply(query)
+	query, args, err := search.ToSQL(b)
+	if err != nil {
+		return err
+	}
 
 	return c.ExecRaw(conn, query, resultFn, args...)
 }
@@ -36,6 +39,7 @@ func (c *Context) ExecRaw(conn *sqlite.Conn, query string, resultFn ResultFn, ar
 		startTime = time.Now()
 	}
 
+	c.checkScans(conn, query, args)
 	err := sqliteutil.Exec(conn, query, resultFn, args...)
 
 	if c.Log {
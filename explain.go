@@ -0,0 +1,260 @@
+package hades
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqliteutil"
+	"github.com/pkg/errors"
+)
+
+// QueryPlan is the result of EXPLAIN QUERY PLAN for a query.
+type QueryPlan struct {
+	// Nodes are the top-level steps of the plan
+	Nodes []*QueryPlanNode
+}
+
+// QueryPlanNode is a step of a QueryPlan, for example
+// "SEARCH TABLE games USING INTEGER PRIMARY KEY (rowid=?)"
+type QueryPlanNode struct {
+	ID       int64
+	Detail   string
+	Children []*QueryPlanNode
+}
+
+// FullScan is a query step that reads a whole table.
+type FullScan struct {
+	Query  string
+	Table  string
+	Detail string
+	// Rows is the number of rows in the table, up to the
+	// threshold it was checked against.
+	Rows int64
+}
+
+// ExplainQueryPlan returns how SQLite would run query, without running it.
+func (c *Context) ExplainQueryPlan(conn *sqlite.Conn, query string, args ...interface{}) (*QueryPlan, error) {
+	plan := &QueryPlan{}
+	nodes := make(map[int64]*QueryPlanNode)
+
+	err := sqliteutil.Exec(conn, "EXPLAIN QUERY PLAN "+query, func(stmt *sqlite.Stmt) error {
+		node := &QueryPlanNode{
+			ID:     stmt.ColumnInt64(0),
+			Detail: stmt.ColumnText(3),
+		}
+		nodes[node.ID] = node
+
+		if parent, ok := nodes[stmt.ColumnInt64(1)]; ok {
+			parent.Children = append(parent.Children, node)
+		} else {
+			plan.Nodes = append(plan.Nodes, node)
+		}
+		return nil
+	}, args...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "explaining query plan")
+	}
+	return plan, nil
+}
+
+// Walk calls fn for each node of the plan, parents first.
+func (qp *QueryPlan) Walk(fn func(node *QueryPlanNode)) {
+	var walk func(nodes []*QueryPlanNode)
+	walk = func(nodes []*QueryPlanNode) {
+		for _, node := range nodes {
+			fn(node)
+			walk(node.Children)
+		}
+	}
+	walk(qp.Nodes)
+}
+
+// String formats the plan like the sqlite3 shell does.
+func (qp *QueryPlan) String() string {
+	var lines []string
+	var format func(nodes []*QueryPlanNode, depth int)
+	format = func(nodes []*QueryPlanNode, depth int) {
+		for _, node := range nodes {
+			lines = append(lines, strings.Repeat("  ", depth)+node.Detail)
+			format(node.Children, depth+1)
+		}
+	}
+	format(qp.Nodes, 0)
+	return strings.Join(lines, "\n")
+}
+
+// scannedTable returns the table a plan step reads in full, if any.
+// Depending on the SQLite version, those steps look like
+// "SCAN TABLE games" or "SCAN games".
+func scannedTable(detail string) (string, bool) {
+	if !strings.HasPrefix(detail, "SCAN ") {
+		return "", false
+	}
+	words := strings.Fields(strings.TrimPrefix(detail, "SCAN "))
+	if len(words) > 0 && words[0] == "TABLE" {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return "", false
+	}
+	switch words[0] {
+	case "CONSTANT", "SUBQUERY":
+		return "", false
+	}
+	return words[0], true
+}
+
+// fullScans returns the steps of query's plan that read a whole table
+// with at least minRows rows. Tables that can't be counted, like
+// common table expressions, are skipped.
+func (c *Context) fullScans(conn *sqlite.Conn, query string, args []interface{}, minRows int64) ([]FullScan, error) {
+	plan, err := c.ExplainQueryPlan(conn, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	var scans []FullScan
+	plan.Walk(func(node *QueryPlanNode) {
+		table, ok := scannedTable(node.Detail)
+		if !ok {
+			return
+		}
+
+		var rows int64
+		countQuery := fmt.Sprintf("SELECT count(*) FROM (SELECT 1 FROM %s LIMIT %d)", EscapeIdentifier(table), minRows)
+		err := sqliteutil.Exec(conn, countQuery, func(stmt *sqlite.Stmt) error {
+			rows = stmt.ColumnInt64(0)
+			return nil
+		})
+		if err != nil || rows < minRows {
+			return
+		}
+
+		scans = append(scans, FullScan{
+			Query:  query,
+			Table:  table,
+			Detail: node.Detail,
+			Rows:   rows,
+		})
+	})
+	return scans, nil
+}
+
+// scanChecker remembers which queries had their plan checked,
+// and whether full scans are being recorded.
+type scanChecker struct {
+	l         sync.Mutex
+	seen      map[string]bool
+	recording *scanRecording
+}
+
+type scanRecording struct {
+	minRows int64
+	seen    map[string]bool
+	scans   []FullScan
+}
+
+// checkScans is called by ExecRaw before running query.
+func (c *Context) checkScans(conn *sqlite.Conn, query string, args []interface{}) {
+	if c.scans == nil || (c.FullScanThreshold <= 0 && c.scans.recorder() == nil) {
+		return
+	}
+
+	sc := c.scans
+	sc.l.Lock()
+	warn := c.FullScanThreshold > 0 && !sc.seen[query]
+	if warn {
+		sc.seen[query] = true
+	}
+	rec := sc.recording
+	record := rec != nil && !rec.seen[query]
+	if record {
+		rec.seen[query] = true
+	}
+	sc.l.Unlock()
+
+	if warn {
+		scans, err := c.fullScans(conn, query, args, c.FullScanThreshold)
+		if err != nil {
+			c.Consumer.Debugf("hades: could not check query plan: %+v", err)
+		}
+		for _, scan := range scans {
+			c.Consumer.Warnf("hades: full scan of table %s (%d+ rows): %s -- in query: %s", scan.Table, scan.Rows, scan.Detail, query)
+		}
+	}
+
+	if record {
+		scans, err := c.fullScans(conn, query, args, rec.minRows)
+		if err != nil {
+			c.Consumer.Debugf("hades: could not check query plan: %+v", err)
+		}
+		sc.l.Lock()
+		rec.scans = append(rec.scans, scans...)
+		sc.l.Unlock()
+	}
+}
+
+func (sc *scanChecker) recorder() *scanRecording {
+	if sc == nil {
+		return nil
+	}
+	sc.l.Lock()
+	defer sc.l.Unlock()
+	return sc.recording
+}
+
+// RecordFullScans runs fn, and returns the full scans of tables with at
+// least minRows rows done by queries run through c in the meantime,
+// for example to check in tests that queries use indices:
+//
+//   scans, err := c.RecordFullScans(0, func() error {
+//     return c.Select(conn, &games, builder.Eq{"user_id": 12}, hades.Search{})
+//   })
+//   wtest.Must(t, err)
+//   assert.Empty(t, scans)
+//
+// Unlike FullScanThreshold, it checks every distinct query run by fn,
+// even those that ran before, and doesn't log anything. Recordings
+// can't be nested.
+func (c *Context) RecordFullScans(minRows int64, fn func() error) ([]FullScan, error) {
+	if c.scans == nil {
+		return nil, errors.Errorf("RecordFullScans needs a Context created with NewContext")
+	}
+
+	sc := c.scans
+	rec := &scanRecording{
+		minRows: minRows,
+		seen:    make(map[string]bool),
+	}
+
+	sc.l.Lock()
+	if sc.recording != nil {
+		sc.l.Unlock()
+		return nil, errors.Errorf("RecordFullScans can't be nested")
+	}
+	sc.recording = rec
+	sc.l.Unlock()
+
+	defer func() {
+		sc.l.Lock()
+		sc.recording = nil
+		sc.l.Unlock()
+	}()
+
+	err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	sc.l.Lock()
+	defer sc.l.Unlock()
+	return rec.scans, nil
+}
+
+func newScanChecker() *scanChecker {
+	return &scanChecker{
+		seen: make(map[string]bool),
+	}
+}
@@ -0,0 +1,78 @@
+package hades_test
+
+import (
+	"strings"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/state"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExplainQueryPlan(t *testing.T) {
+	type Game struct {
+		ID     int64
+		UserID int64
+	}
+
+	models := []interface{}{&Game{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		var games []*Game
+		for i := int64(1); i <= 10; i++ {
+			games = append(games, &Game{ID: i, UserID: i % 2})
+		}
+		wtest.Must(t, c.Save(conn, games))
+
+		plan, err := c.ExplainQueryPlan(conn, "SELECT * FROM games WHERE id = ?", 1)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, len(plan.Nodes))
+		assert.True(t, strings.HasPrefix(plan.Nodes[0].Detail, "SEARCH"), "looking up by primary key must not scan")
+
+		plan, err = c.ExplainQueryPlan(conn, "SELECT * FROM games WHERE user_id = ?", 1)
+		wtest.Must(t, err)
+		assert.True(t, strings.HasPrefix(plan.String(), "SCAN"), "looking up by user_id must scan")
+
+		scans, err := c.RecordFullScans(5, func() error {
+			var games []*Game
+			for i := 0; i < 3; i++ {
+				err := c.Select(conn, &games, builder.Eq{"user_id": 1}, hades.Search{})
+				if err != nil {
+					return err
+				}
+			}
+			return c.Get(conn, &Game{}, 4)
+		})
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, len(scans), "must record each distinct query once")
+		assert.EqualValues(t, "games", scans[0].Table)
+		assert.Contains(t, scans[0].Query, "user_id")
+
+		scans, err = c.RecordFullScans(100, func() error {
+			var games []*Game
+			return c.Select(conn, &games, builder.Eq{"user_id": 1}, hades.Search{})
+		})
+		wtest.Must(t, err)
+		assert.Empty(t, scans, "must ignore small tables")
+
+		var warnings []string
+		c.Consumer = &state.Consumer{
+			OnMessage: func(lvl string, msg string) {
+				if lvl == "warning" {
+					warnings = append(warnings, msg)
+				}
+			},
+		}
+		c.FullScanThreshold = 5
+		for i := 0; i < 3; i++ {
+			_, err := c.Count(conn, &Game{}, builder.Eq{"user_id": 1})
+			wtest.Must(t, err)
+			wtest.Must(t, c.Get(conn, &Game{}, 4))
+		}
+		assert.EqualValues(t, 1, len(warnings), "must warn once per distinct query")
+		assert.Contains(t, warnings[0], "games")
+	})
+}
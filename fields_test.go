@@ -0,0 +1,100 @@
+package hades_test
+
+import (
+	"runtime"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SelectFields(t *testing.T) {
+	type Game struct {
+		ID          int64
+		Title       string
+		Description string
+		Downloads   int64
+	}
+
+	models := []interface{}{&Game{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 1, Title: "X-Moon", Description: "A very long description", Downloads: 100},
+			&Game{ID: 2, Title: "butler", Description: "Another long description", Downloads: 200},
+		}))
+
+		var games []*Game
+		wtest.Must(t, c.Select(conn, &games, builder.NewCond(), hades.Search{}.Fields("Title", "downloads").OrderBy("id ASC")))
+		assert.EqualValues(t, 2, len(games))
+		assert.EqualValues(t, 1, games[0].ID, "primary keys are always loaded")
+		assert.EqualValues(t, "X-Moon", games[0].Title)
+		assert.EqualValues(t, 100, games[0].Downloads)
+		assert.EqualValues(t, "", games[0].Description)
+
+		err := c.Select(conn, &games, builder.NewCond(), hades.Search{}.Fields("Developer"))
+		assert.Error(t, err, "must refuse unknown fields")
+
+		// saving partially-loaded records doesn't blank unloaded columns
+		games[0].Title = "X-Moon (remastered)"
+		games[1].Downloads = 201
+		wtest.Must(t, c.Save(conn, games))
+
+		g := &Game{}
+		found, err := c.SelectOne(conn, g, builder.Eq{"id": 1})
+		wtest.Must(t, err)
+		assert.True(t, found)
+		assert.EqualValues(t, "X-Moon (remastered)", g.Title)
+		assert.EqualValues(t, "A very long description", g.Description)
+
+		found, err = c.SelectOne(conn, g, builder.Eq{"id": 2})
+		wtest.Must(t, err)
+		assert.True(t, found)
+		assert.EqualValues(t, 201, g.Downloads)
+		assert.EqualValues(t, "Another long description", g.Description)
+
+		// records loaded in full save in full
+		g.Description = ""
+		wtest.Must(t, c.Save(conn, g))
+		var descriptions []string
+		wtest.Must(t, c.Pluck(conn, &Game{}, "Description", &descriptions, builder.NewCond(), hades.Search{}.OrderBy("id ASC")))
+		assert.EqualValues(t, []string{"A very long description", ""}, descriptions)
+
+		// loading a record in full clears the partial flag
+		partial, err := hades.SelectAll[Game](c, conn, builder.Eq{"id": 1}, hades.Search{}.Fields("Title"))
+		wtest.Must(t, err)
+		found, err = c.SelectOne(conn, partial[0], builder.Eq{"id": 1})
+		wtest.Must(t, err)
+		assert.True(t, found)
+		partial[0].Description = "Short"
+		wtest.Must(t, c.Save(conn, partial[0]))
+
+		descriptions = nil
+		wtest.Must(t, c.Pluck(conn, &Game{}, "Description", &descriptions, builder.Eq{"id": 1}, hades.Search{}))
+		assert.EqualValues(t, []string{"Short"}, descriptions)
+
+		// so does Query.First
+		first := &Game{}
+		found, err = c.Query(&Game{}).Where(builder.Eq{"id": 2}).Search(hades.Search{}.Fields("ID", "Title")).First(conn, first)
+		wtest.Must(t, err)
+		assert.True(t, found)
+		first.Title = "butler (daily)"
+		wtest.Must(t, c.Save(conn, first))
+
+		g = &Game{}
+		wtest.Must(t, c.Get(conn, g, int64(2)))
+		assert.EqualValues(t, "butler (daily)", g.Title)
+		assert.EqualValues(t, 201, g.Downloads, "Query.First must keep the partial flag")
+
+		// brand new records are saved in full
+		partial = nil
+		runtime.GC()
+		wtest.Must(t, c.Save(conn, &Game{ID: 3, Title: "itch", Description: "Fresh"}))
+		descriptions = nil
+		wtest.Must(t, c.Pluck(conn, &Game{}, "Description", &descriptions, builder.Eq{"id": 3}, hades.Search{}))
+		assert.EqualValues(t, []string{"Fresh"}, descriptions)
+	})
+}
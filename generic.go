@@ -0,0 +1,79 @@
+package hades
+
+import (
+	"fmt"
+	"reflect"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// The functions in this file are typed counterparts of Context methods.
+// Go has no way to express "T is registered with this context" as a type
+// constraint, so that's still checked at runtime, but results don't need
+// to be passed as interface{} or type-asserted anymore.
+
+// SelectAll returns all records of model T matching cond.
+//
+//   games, err := hades.SelectAll[Game](c, conn, builder.Eq{"user_id": 12}, hades.Search{})
+//
+func SelectAll[T any](c *Context, conn *sqlite.Conn, cond builder.Cond, search Search) ([]*T, error) {
+	var result []*T
+	err := c.Select(conn, &result, cond, search)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Get returns the record of model T with the given primary key.
+// Models with composite primary keys take one value per primary
+// field, in the order they're declared in:
+//
+//   game, err := hades.Get[Game](c, conn, 24)
+//   cg, err := hades.Get[CollectionGame](c, conn, collectionID, gameID)
+//
+// Like Context.Get, if there's no such record, Get returns a
+// *NotFoundError, see IsNotFound.
+func Get[T any](c *Context, conn *sqlite.Conn, pk ...interface{}) (*T, error) {
+	result := new(T)
+	err := c.Get(conn, result, pk...)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// Count returns the number of records of model T matching cond.
+func Count[T any](c *Context, conn *sqlite.Conn, cond builder.Cond) (int64, error) {
+	_, err := c.scopeByType(reflect.TypeOf((*T)(nil)))
+	if err != nil {
+		return 0, err
+	}
+	return c.Count(conn, new(T), cond)
+}
+
+// DeleteWhere deletes all records of model T matching cond, and returns
+// how many were deleted. Like Delete, it refuses an empty cond.
+func DeleteWhere[T any](c *Context, conn *sqlite.Conn, cond builder.Cond) (int64, error) {
+	return c.Delete(conn, new(T), cond)
+}
+
+// primaryKeyCond returns a condition matching the record of ms
+// with the given primary key values, in PrimaryFields order.
+func (c *Context) primaryKeyCond(ms *ModelStruct, pk []interface{}) (builder.Cond, error) {
+	if len(ms.PrimaryFields) == 0 {
+		return nil, errors.Errorf("%v has no primary key", ms.ModelType)
+	}
+	if len(pk) != len(ms.PrimaryFields) {
+		return nil, errors.Errorf("%v has %d primary key fields, but %d values were given", ms.ModelType, len(ms.PrimaryFields), len(pk))
+	}
+
+	eq := make(builder.Eq)
+	for i, sf := range ms.PrimaryFields {
+		column := fmt.Sprintf("%s.%s", EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName))
+		eq[column] = c.encodeValue(sf, pk[i])
+	}
+	return eq, nil
+}
@@ -0,0 +1,85 @@
+package hades_test
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Generic(t *testing.T) {
+	type Game struct {
+		ID    int64
+		Title string
+	}
+
+	type ProfileData struct {
+		ProfileID int64  `hades:"primary_key"`
+		Key       string `hades:"primary_key"`
+		Value     string
+	}
+
+	type Stranger struct {
+		ID int64
+	}
+
+	models := []interface{}{&Game{}, &ProfileData{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 1, Title: "Jazz Jackrabbit"},
+			&Game{ID: 2, Title: "Duke Nukem 2"},
+			&Game{ID: 3, Title: "Commander Keen"},
+		}))
+		wtest.Must(t, c.Save(conn, []*ProfileData{
+			&ProfileData{ProfileID: 14, Key: "foo", Value: "bar"},
+			&ProfileData{ProfileID: 14, Key: "baz", Value: "qux"},
+		}))
+
+		games, err := hades.SelectAll[Game](c, conn, builder.Gte{"id": 2}, hades.Search{}.OrderBy("id ASC"))
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, len(games))
+		assert.EqualValues(t, "Duke Nukem 2", games[0].Title)
+
+		game, err := hades.Get[Game](c, conn, 3)
+		wtest.Must(t, err)
+		assert.EqualValues(t, "Commander Keen", game.Title)
+
+		game, err = hades.Get[Game](c, conn, 404)
+		assert.True(t, hades.IsNotFound(err), "must return a NotFoundError")
+		assert.Nil(t, game)
+
+		pd, err := hades.Get[ProfileData](c, conn, 14, "baz")
+		wtest.Must(t, err)
+		assert.EqualValues(t, "qux", pd.Value)
+
+		_, err = hades.Get[ProfileData](c, conn, 14)
+		assert.Error(t, err, "must refuse partial composite keys")
+
+		count, err := hades.Count[Game](c, conn, builder.NewCond())
+		wtest.Must(t, err)
+		assert.EqualValues(t, 3, count)
+
+		deleted, err := hades.DeleteWhere[Game](c, conn, builder.Eq{"id": 1})
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, deleted)
+		count, err = hades.Count[Game](c, conn, builder.NewCond())
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count)
+
+		_, err = hades.DeleteWhere[Game](c, conn, builder.NewCond())
+		assert.Error(t, err, "must refuse to blindly delete")
+
+		_, err = hades.SelectAll[Stranger](c, conn, builder.NewCond(), hades.Search{})
+		assert.Error(t, err, "must refuse unregistered models")
+		_, err = hades.Get[Stranger](c, conn, 1)
+		assert.Error(t, err, "must refuse unregistered models")
+		_, err = hades.Count[Stranger](c, conn, builder.NewCond())
+		assert.Error(t, err, "must refuse unregistered models")
+		_, err = hades.DeleteWhere[Stranger](c, conn, builder.Eq{"id": 1})
+		assert.Error(t, err, "must refuse unregistered models")
+	})
+}
@@ -0,0 +1,217 @@
+package hades
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// NotFoundError is returned by Get when there's no record with the
+// given primary key.
+type NotFoundError struct {
+	ModelType reflect.Type
+	Key       []interface{}
+}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("no %v with primary key %v", e.ModelType, e.Key)
+}
+
+// IsNotFound returns true if err is (or wraps) a NotFoundError.
+func IsNotFound(err error) bool {
+	_, ok := errors.Cause(err).(*NotFoundError)
+	return ok
+}
+
+// Get loads the record with the given primary key into dest, a *Model.
+// Models with composite primary keys take one value per primary field,
+// in the order they're declared in:
+//
+//   game := &Game{}
+//   err := c.Get(conn, game, 24)
+//   cg := &CollectionGame{}
+//   err = c.Get(conn, cg, collectionID, gameID)
+//
+// If there's no such record, Get returns a *NotFoundError.
+func (c *Context) Get(conn *sqlite.Conn, dest interface{}, pk ...interface{}) error {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() {
+		return errors.Errorf("Get expects dest to be a *Model, but it got a %v", reflect.TypeOf(dest))
+	}
+
+	scope, err := c.scopeByType(destVal.Type())
+	if err != nil {
+		return err
+	}
+	ms := scope.GetModelStruct()
+
+	cond, err := c.primaryKeyCond(ms, pk)
+	if err != nil {
+		return err
+	}
+
+	found, err := c.SelectOne(conn, dest, cond)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.WithStack(&NotFoundError{ModelType: ms.ModelType, Key: pk})
+	}
+	return nil
+}
+
+// GetMany loads the records with the given primary keys into destSlice,
+// a *[]*Model, in the same order as pks. For models with composite
+// primary keys, each key is a []interface{} with one value per
+// primary field:
+//
+//   var games []*Game
+//   err := c.GetMany(conn, &games, []interface{}{24, 46, 48})
+//   var cgs []*CollectionGame
+//   err = c.GetMany(conn, &cgs, []interface{}{
+//     []interface{}{collectionID, 24},
+//     []interface{}{collectionID, 46},
+//   })
+//
+// Keys that don't match any record are skipped, and keys given
+// several times only yield their record once.
+func (c *Context) GetMany(conn *sqlite.Conn, destSlice interface{}, pks []interface{}) error {
+	destVal := reflect.ValueOf(destSlice)
+	if destVal.Kind() != reflect.Ptr || destVal.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("GetMany expects destSlice to be a *[]*Model, but it got a %v", reflect.TypeOf(destSlice))
+	}
+	sliceVal := destVal.Elem()
+	if sliceVal.Type().Elem().Kind() != reflect.Ptr {
+		return errors.Errorf("GetMany expects destSlice to be a *[]*Model, but it got a %v", destVal.Type())
+	}
+
+	scope, err := c.scopeByType(sliceVal.Type().Elem())
+	if err != nil {
+		return err
+	}
+	ms := scope.GetModelStruct()
+	numPK := len(ms.PrimaryFields)
+	if numPK == 0 {
+		return errors.Errorf("%v has no primary key", ms.ModelType)
+	}
+
+	encodedKeys := make([][]interface{}, len(pks))
+	var keys [][]interface{}
+	seen := make(map[string]bool)
+	for i, pk := range pks {
+		key, ok := pk.([]interface{})
+		if !ok {
+			key = []interface{}{pk}
+		}
+		if len(key) != numPK {
+			return errors.Errorf("%v has %d primary key fields, but key %d has %d values", ms.ModelType, numPK, i, len(key))
+		}
+
+		encoded := make([]interface{}, numPK)
+		for j, sf := range ms.PrimaryFields {
+			encoded[j] = c.encodeValue(sf, key[j])
+		}
+		encodedKeys[i] = encoded
+
+		id := primaryKeyID(encoded)
+		if !seen[id] {
+			seen[id] = true
+			keys = append(keys, encoded)
+		}
+	}
+
+	byKey := make(map[string]reflect.Value)
+	pageSize := maxSqlVars / numPK
+	for len(keys) > 0 {
+		n := pageSize
+		if len(keys) < n {
+			n = len(keys)
+		}
+
+		pageAddr := reflect.New(sliceVal.Type())
+		err := c.Select(conn, pageAddr.Interface(), primaryKeysCond(ms, keys[:n]), Search{})
+		if err != nil {
+			return errors.WithMessage(err, "performing page fetch")
+		}
+
+		page := pageAddr.Elem()
+		for i := 0; i < page.Len(); i++ {
+			rec := page.Index(i)
+			byKey[primaryKeyID(c.recordPrimaryKey(ms, rec))] = rec
+		}
+		keys = keys[n:]
+	}
+
+	result := reflect.MakeSlice(sliceVal.Type(), 0, len(byKey))
+	for _, encoded := range encodedKeys {
+		id := primaryKeyID(encoded)
+		if rec, ok := byKey[id]; ok {
+			result = reflect.Append(result, rec)
+			// only once
+			delete(byKey, id)
+		}
+	}
+	sliceVal.Set(result)
+	return nil
+}
+
+// primaryKeysCond returns a condition matching the records of ms with
+// any of the given (already encoded) primary keys.
+func primaryKeysCond(ms *ModelStruct, keys [][]interface{}) builder.Cond {
+	var columns []string
+	for _, sf := range ms.PrimaryFields {
+		columns = append(columns, fmt.Sprintf("%s.%s", EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName)))
+	}
+
+	if len(columns) == 1 {
+		var values []interface{}
+		for _, key := range keys {
+			values = append(values, key[0])
+		}
+		return builder.In(columns[0], values...)
+	}
+
+	// (a, b) IN (VALUES (?, ?), (?, ?))
+	var args []interface{}
+	tuple := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	tuples := make([]string, len(keys))
+	for i, key := range keys {
+		tuples[i] = tuple
+		args = append(args, key...)
+	}
+	return builder.Expr(fmt.Sprintf("(%s) IN (VALUES %s)",
+		strings.Join(columns, ", "),
+		strings.Join(tuples, ", "),
+	), args...)
+}
+
+// recordPrimaryKey returns the encoded primary key values of rec, a *Model.
+func (c *Context) recordPrimaryKey(ms *ModelStruct, rec reflect.Value) []interface{} {
+	var values []interface{}
+	for _, sf := range ms.PrimaryFields {
+		values = append(values, c.encodeValue(sf, rec.Elem().FieldByName(sf.Name).Interface()))
+	}
+	return values
+}
+
+// primaryKeyID turns encoded primary key values into a map key,
+// so that for example int(24) and int64(24) are the same key.
+func primaryKeyID(values []interface{}) string {
+	var parts []string
+	for _, v := range values {
+		rv := reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			parts = append(parts, fmt.Sprintf("i%d", rv.Int()))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			parts = append(parts, fmt.Sprintf("i%d", int64(rv.Uint())))
+		default:
+			parts = append(parts, fmt.Sprintf("%T:%v", v, v))
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
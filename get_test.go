@@ -0,0 +1,73 @@
+package hades_test
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Get(t *testing.T) {
+	type Game struct {
+		ID    int64
+		Title string
+	}
+
+	type CollectionGame struct {
+		CollectionID int64 `hades:"primary_key"`
+		GameID       int64 `hades:"primary_key"`
+		Position     int64
+	}
+
+	models := []interface{}{&Game{}, &CollectionGame{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		var games []*Game
+		var cgs []*CollectionGame
+		for i := int64(1); i <= 1000; i++ {
+			games = append(games, &Game{ID: i, Title: "game"})
+			cgs = append(cgs, &CollectionGame{CollectionID: i % 3, GameID: i, Position: i * 10})
+		}
+		wtest.Must(t, c.Save(conn, games))
+		wtest.Must(t, c.Save(conn, cgs))
+
+		game := &Game{}
+		wtest.Must(t, c.Get(conn, game, 24))
+		assert.EqualValues(t, 24, game.ID)
+
+		err := c.Get(conn, &Game{}, 4040)
+		assert.Error(t, err)
+		assert.True(t, hades.IsNotFound(err), "missing records must give a not-found error")
+		assert.False(t, hades.IsNotFound(c.Get(conn, &Game{}, 1, 2)), "bad keys are a different error")
+
+		cg := &CollectionGame{}
+		wtest.Must(t, c.Get(conn, cg, 1, 4))
+		assert.EqualValues(t, 40, cg.Position)
+		assert.True(t, hades.IsNotFound(c.Get(conn, &CollectionGame{}, 2, 4)))
+
+		var fetched []*Game
+		wtest.Must(t, c.GetMany(conn, &fetched, []interface{}{48, 4040, 12, 48, int32(3)}))
+		var ids []int64
+		for _, g := range fetched {
+			ids = append(ids, g.ID)
+		}
+		assert.EqualValues(t, []int64{48, 12, 3}, ids, "must keep key order, skip missing and duplicate keys")
+
+		var keys []interface{}
+		for i := int64(1000); i >= 1; i-- {
+			keys = append(keys, []interface{}{i % 3, i})
+		}
+		keys = append(keys, []interface{}{int64(1), int64(3)})
+
+		var fetchedCGs []*CollectionGame
+		wtest.Must(t, c.GetMany(conn, &fetchedCGs, keys))
+		assert.EqualValues(t, 1000, len(fetchedCGs), "must fetch composite keys across several pages")
+		assert.EqualValues(t, 1000, fetchedCGs[0].GameID)
+		assert.EqualValues(t, 1, fetchedCGs[999].GameID)
+
+		err = c.GetMany(conn, &fetchedCGs, []interface{}{1})
+		assert.Error(t, err, "must refuse partial composite keys")
+	})
+}
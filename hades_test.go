@@ -42,3 +42,32 @@ func withContext(t *testing.T, models []interface{}, f WithContextFunc) {
 
 	f(conn, c)
 }
+
+func withBenchContext(b *testing.B, models []interface{}, f WithContextFunc) {
+	dbpool, err := sqlite.Open("file:memory:?mode=memory", 0, 10)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer dbpool.Close()
+
+	conn := dbpool.Get(context.Background().Done())
+	defer dbpool.Put(conn)
+
+	c, err := hades.NewContext(&state.Consumer{}, models...)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	err = c.AutoMigrate(conn)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	defer func() {
+		c.ScopeMap.Each(func(scope *hades.Scope) error {
+			return c.ExecRaw(conn, "DROP TABLE "+scope.TableName(), nil)
+		})
+	}()
+
+	f(conn, c)
+}
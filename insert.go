@@ -8,6 +8,12 @@ import (
 )
 
 func (scope *Scope) ToEq(rec reflect.Value) builder.Eq {
+	return scope.toEq(rec, nil)
+}
+
+// toEq returns the values of the given columns of rec, along with its
+// primary keys, or of all columns if columns is nil
+func (scope *Scope) toEq(rec reflect.Value, columns map[string]bool) builder.Eq {
 	recEl := rec
 
 	if recEl.Type().Kind() == reflect.Ptr {
@@ -32,7 +38,10 @@ func (scope *Scope) ToEq(rec reflect.Value) builder.Eq {
 		if !sf.IsNormal {
 			return
 		}
-		eq[EscapeIdentifier(sf.DBName)] = DBValue(field.Interface())
+		if columns != nil && !sf.IsPrimaryKey && !columns[sf.DBName] {
+			return
+		}
+		eq[EscapeIdentifier(sf.DBName)] = scope.ctx.encodeValue(sf, field.Interface())
 	}
 
 	for _, sf := range scope.GetModelStruct().StructFields {
@@ -41,7 +50,17 @@ func (scope *Scope) ToEq(rec reflect.Value) builder.Eq {
 	return eq
 }
 
-func (c *Context) Insert(conn *sqlite.Conn, scope *Scope, rec reflect.Value) error {
-	eq := scope.ToEq(rec)
-	return c.Exec(conn, builder.Insert(eq).Into(scope.TableName()), nil)
+// Insert inserts rec, a *Model of scope. Unless OnConflict says otherwise,
+// it fails if rec conflicts with an existing record.
+func (c *Context) Insert(conn *sqlite.Conn, scope *Scope, rec reflect.Value, opts ...InsertParam) error {
+	var params insertParams
+	for _, o := range opts {
+		o.ApplyToInsertParams(&params)
+	}
+
+	cf, err := params.conflict.resolve(scope.GetModelStruct(), OnConflictFail)
+	if err != nil {
+		return err
+	}
+	return c.upsert(conn, scope, rec, nil, cf)
 }
@@ -0,0 +1,176 @@
+package hades
+
+import (
+	"fmt"
+	"iter"
+	"reflect"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// SelectEach is like Select, but calls fn with each record (a *Model) as
+// it's scanned, instead of collecting them all in a slice:
+//
+//   err := c.SelectEach(conn, &Download{}, builder.NewCond(), hades.Search{}, func(rec interface{}) error {
+//     total += rec.(*Download).Size
+//     return nil
+//   })
+//
+// If fn returns ErrStop, iteration stops and SelectEach returns nil.
+// Any other error stops iteration and is returned.
+//
+// The query is still running while fn is called, so fn may run other
+// queries, but should not run that same query.
+func (c *Context) SelectEach(conn *sqlite.Conn, model interface{}, cond builder.Cond, search Search, fn func(rec interface{}) error, opts ...IterParam) error {
+	params := &iterParams{}
+	for _, o := range opts {
+		o.ApplyToIterParams(params)
+	}
+
+	scope, err := c.scopeByType(reflect.TypeOf(model))
+	if err != nil {
+		return err
+	}
+
+	return c.selectEach(conn, scope, cond, search, params.reuseRecord, func(rec reflect.Value) error {
+		return fn(rec.Interface())
+	})
+}
+
+// SelectIter returns an iterator over the records of model T matching
+// cond, for use with range:
+//
+//   for download, err := range hades.SelectIter[Download](c, conn, cond, hades.Search{}) {
+//     if err != nil {
+//       return err
+//     }
+//     total += download.Size
+//   }
+//
+// Breaking out of the loop stops the query. If an error occurs,
+// it's yielded once along with a nil record, and iteration stops.
+func SelectIter[T any](c *Context, conn *sqlite.Conn, cond builder.Cond, search Search, opts ...IterParam) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		params := &iterParams{}
+		for _, o := range opts {
+			o.ApplyToIterParams(params)
+		}
+
+		scope, err := c.scopeByType(reflect.TypeOf((*T)(nil)))
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+
+		err = c.selectEach(conn, scope, cond, search, params.reuseRecord, func(rec reflect.Value) error {
+			if !yield(rec.Interface().(*T), nil) {
+				return ErrStop
+			}
+			return nil
+		})
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+// SelectBatches calls fn with batches of up to size records of model
+// matching cond. Each batch is a fresh []*Model, with the associations
+// specified by preloads already loaded. The last batch may be smaller.
+//
+// If fn returns ErrStop, iteration stops and SelectBatches returns nil.
+func (c *Context) SelectBatches(conn *sqlite.Conn, model interface{}, cond builder.Cond, search Search, size int, fn func(batch interface{}) error, preloads ...PreloadParam) error {
+	if size <= 0 {
+		return errors.Errorf("SelectBatches expects a positive batch size, got %d", size)
+	}
+
+	scope, err := c.scopeByType(reflect.TypeOf(model))
+	if err != nil {
+		return err
+	}
+	sliceTyp := reflect.SliceOf(reflect.PtrTo(scope.GetModelStruct().ModelType))
+
+	batch := reflect.MakeSlice(sliceTyp, 0, size)
+	flush := func() error {
+		if len(preloads) > 0 {
+			err := c.Preload(conn, batch.Interface(), preloads...)
+			if err != nil {
+				return errors.WithMessage(err, "preloading batch")
+			}
+		}
+		err := fn(batch.Interface())
+		batch = reflect.MakeSlice(sliceTyp, 0, size)
+		return err
+	}
+
+	err = c.selectEach(conn, scope, cond, search, false, func(rec reflect.Value) error {
+		batch = reflect.Append(batch, rec)
+		if batch.Len() < size {
+			return nil
+		}
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	if batch.Len() > 0 {
+		err = flush()
+		if errors.Cause(err) == ErrStop {
+			return nil
+		}
+	}
+	return err
+}
+
+func (c *Context) selectEach(conn *sqlite.Conn, scope *Scope, cond builder.Cond, search Search, reuseRecord bool, fn func(rec reflect.Value) error) error {
+	ms := scope.GetModelStruct()
+	sel, err := c.selectQuery(ms, cond, search)
+	if err != nil {
+		return err
+	}
+
+	var rec reflect.Value
+	if reuseRecord {
+		rec = reflect.New(ms.ModelType)
+	}
+
+	row := 0
+	err = c.ExecRaw(conn, sel.query, func(stmt *sqlite.Stmt) error {
+		if reuseRecord {
+			rec.Elem().Set(reflect.Zero(ms.ModelType))
+		} else {
+			rec = reflect.New(ms.ModelType)
+		}
+
+		err := c.scanSelected(stmt, sel, rec)
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("scanning row %d", row))
+		}
+		row++
+		return fn(rec)
+	}, sel.args...)
+	if errors.Cause(err) == ErrStop {
+		return nil
+	}
+	return err
+}
+
+// scopeByType returns the scope for a registered model, given
+// the type of either Model or *Model.
+func (c *Context) scopeByType(typ reflect.Type) (*Scope, error) {
+	if typ == nil {
+		return nil, errors.Errorf("expected a model, got nil")
+	}
+	if typ.Kind() != reflect.Ptr {
+		typ = reflect.PtrTo(typ)
+	}
+
+	scope := c.ScopeMap.ByType(typ)
+	if scope == nil {
+		return nil, errors.Errorf("%v is not a model known to this hades context", typ)
+	}
+	return scope, nil
+}
@@ -0,0 +1,128 @@
+package hades_test
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SelectEach(t *testing.T) {
+	type Upload struct {
+		ID         int64
+		DownloadID int64
+	}
+
+	type Download struct {
+		ID      int64
+		Size    int64
+		Uploads []*Upload
+	}
+
+	models := []interface{}{&Download{}, &Upload{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		var downloads []*Download
+		for i := int64(1); i <= 10; i++ {
+			downloads = append(downloads, &Download{
+				ID:   i,
+				Size: i * 100,
+				Uploads: []*Upload{
+					&Upload{ID: i * 10},
+					&Upload{ID: i*10 + 1},
+				},
+			})
+		}
+		wtest.Must(t, c.Save(conn, downloads, hades.Assoc("Uploads")))
+
+		var total int64
+		var seen []*Download
+		wtest.Must(t, c.SelectEach(conn, &Download{}, builder.Gt{"id": 2}, hades.Search{}.OrderBy("id ASC"), func(rec interface{}) error {
+			d := rec.(*Download)
+			total += d.Size
+			seen = append(seen, d)
+			return nil
+		}))
+		assert.EqualValues(t, 5200, total)
+		assert.EqualValues(t, 8, len(seen))
+		assert.False(t, seen[0] == seen[1], "records are fresh by default")
+
+		count := 0
+		seen = nil
+		wtest.Must(t, c.SelectEach(conn, &Download{}, builder.NewCond(), hades.Search{}.OrderBy("id ASC"), func(rec interface{}) error {
+			seen = append(seen, rec.(*Download))
+			count++
+			if count == 3 {
+				return hades.ErrStop
+			}
+			return nil
+		}, hades.ReuseRecord()))
+		assert.EqualValues(t, 3, count)
+		assert.True(t, seen[0] == seen[2], "records are reused if asked")
+		assert.EqualValues(t, 3, seen[2].ID)
+
+		err := c.SelectEach(conn, &Download{}, builder.NewCond(), hades.Search{}, func(rec interface{}) error {
+			return assert.AnError
+		})
+		assert.Error(t, err)
+
+		err = c.SelectEach(conn, &struct{ ID int64 }{}, builder.NewCond(), hades.Search{}, func(rec interface{}) error {
+			return nil
+		})
+		assert.Error(t, err, "must refuse unknown models")
+
+		// iterators
+		var ids []int64
+		for d, err := range hades.SelectIter[Download](c, conn, builder.Lte{"id": 4}, hades.Search{}.OrderBy("id DESC")) {
+			wtest.Must(t, err)
+			ids = append(ids, d.ID)
+		}
+		assert.EqualValues(t, []int64{4, 3, 2, 1}, ids)
+
+		ids = nil
+		for d, err := range hades.SelectIter[Download](c, conn, builder.NewCond(), hades.Search{}.OrderBy("id ASC"), hades.ReuseRecord()) {
+			wtest.Must(t, err)
+			ids = append(ids, d.ID)
+			if len(ids) == 2 {
+				break
+			}
+		}
+		assert.EqualValues(t, []int64{1, 2}, ids)
+
+		// the statement was reset, so the same query can run again
+		count = 0
+		for _, err := range hades.SelectIter[Download](c, conn, builder.NewCond(), hades.Search{}.OrderBy("id ASC"), hades.ReuseRecord()) {
+			wtest.Must(t, err)
+			count++
+		}
+		assert.EqualValues(t, 10, count)
+
+		for _, err := range hades.SelectIter[Upload](c, conn, builder.Expr("nope = 1"), hades.Search{}) {
+			assert.Error(t, err)
+		}
+
+		// batches
+		var sizes []int
+		uploads := 0
+		wtest.Must(t, c.SelectBatches(conn, &Download{}, builder.NewCond(), hades.Search{}.OrderBy("id ASC"), 4, func(batch interface{}) error {
+			ds := batch.([]*Download)
+			sizes = append(sizes, len(ds))
+			for _, d := range ds {
+				uploads += len(d.Uploads)
+			}
+			return nil
+		}, hades.Assoc("Uploads")))
+		assert.EqualValues(t, []int{4, 4, 2}, sizes)
+		assert.EqualValues(t, 20, uploads)
+
+		sizes = nil
+		wtest.Must(t, c.SelectBatches(conn, &Download{}, builder.NewCond(), hades.Search{}, 3, func(batch interface{}) error {
+			sizes = append(sizes, len(batch.([]*Download)))
+			return hades.ErrStop
+		}))
+		assert.EqualValues(t, []int{3}, sizes)
+	})
+}
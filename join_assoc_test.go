@@ -0,0 +1,128 @@
+package hades_test
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_JoinAssoc(t *testing.T) {
+	type User struct {
+		ID   int64
+		Name string
+	}
+
+	type Game struct {
+		ID          int64
+		Title       string
+		UserID      int64
+		User        *User
+		PublisherID int64
+		Publisher   *User `hades:"foreign_key:PublisherID"`
+		StudioID    int64
+	}
+
+	type Studio struct {
+		ID    int64
+		Games []*Game
+	}
+
+	type Collection struct {
+		ID    int64
+		Title string
+		Games []*Game `hades:"many_to_many:collection_games"`
+	}
+
+	type CollectionGame struct {
+		CollectionID int64 `hades:"primary_key"`
+		GameID       int64 `hades:"primary_key"`
+	}
+
+	models := []interface{}{&User{}, &Game{}, &Studio{}, &Collection{}, &CollectionGame{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, []*User{
+			&User{ID: 1, Name: "leafo"},
+			&User{ID: 2, Name: "fasterthanlime"},
+			&User{ID: 3, Name: "publisher"},
+		}))
+		xmoon := &Game{ID: 10, Title: "X-Moon", UserID: 1, PublisherID: 3, StudioID: 5}
+		butler := &Game{ID: 20, Title: "butler", UserID: 2, PublisherID: 2, StudioID: 6}
+		itch := &Game{ID: 30, Title: "itch", UserID: 1, PublisherID: 1, StudioID: 6}
+		wtest.Must(t, c.Save(conn, []*Game{xmoon, butler, itch}))
+		wtest.Must(t, c.Save(conn, []*Collection{
+			&Collection{ID: 100, Title: "favorites", Games: []*Game{xmoon, butler}},
+			&Collection{ID: 200, Title: "tools", Games: []*Game{butler}},
+		}, hades.AssocReplace("Games")))
+
+		var games []*Game
+		wtest.Must(t, c.Select(conn, &games,
+			builder.Eq{"users.name": "leafo"},
+			hades.Search{}.JoinAssoc(&Game{}, "User").OrderBy("games.id ASC")))
+		assert.EqualValues(t, 2, len(games), "must join belongs_to")
+		assert.EqualValues(t, 10, games[0].ID)
+		assert.EqualValues(t, 30, games[1].ID)
+
+		games = nil
+		wtest.Must(t, c.Select(conn, &games,
+			builder.Expr("developer.id = publisher.id"),
+			hades.Search{}.
+				JoinAssocAs(&Game{}, "User", "developer").
+				JoinAssocAs(&Game{}, "Publisher", "publisher").
+				OrderBy("games.id ASC")))
+		assert.EqualValues(t, 2, len(games), "must join the same table twice with aliases")
+		assert.EqualValues(t, 20, games[0].ID)
+		assert.EqualValues(t, 30, games[1].ID)
+
+		var collections []*Collection
+		wtest.Must(t, c.Select(conn, &collections,
+			builder.Eq{"games.title": "butler"},
+			hades.Search{}.JoinAssoc(&Collection{}, "Games").OrderBy("collections.id ASC")))
+		assert.EqualValues(t, 2, len(collections), "must join many_to_many")
+
+		collections = nil
+		wtest.Must(t, c.Select(conn, &collections,
+			builder.Eq{"g.title": "X-Moon"},
+			hades.Search{}.JoinAssocAs(&Collection{}, "Games", "g")))
+		assert.EqualValues(t, 1, len(collections), "must join many_to_many with an alias")
+		assert.EqualValues(t, "favorites", collections[0].Title)
+
+		wtest.Must(t, c.Save(conn, []*Studio{{ID: 5}, {ID: 6}, {ID: 7}}))
+		count, err := c.Count(conn, &Studio{},
+			builder.Eq{"games.user_id": 1},
+			hades.Search{}.JoinAssoc(&Studio{}, "Games"))
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count, "must join has_many")
+
+		type row struct {
+			Game `hades:"squash"`
+			User `hades:"squash"`
+		}
+		var rows []*row
+		wtest.Must(t, c.ExecWithSearch(conn,
+			builder.Select("games.*", "publisher.*").From("games"),
+			hades.Search{}.JoinAssocAs(&Game{}, "Publisher", "publisher").OrderBy("games.id ASC"),
+			c.IntoRowsScannerByName(&rows, hades.UnknownColumnsError)))
+		assert.EqualValues(t, 3, len(rows))
+		assert.EqualValues(t, "X-Moon", rows[0].Title)
+		assert.EqualValues(t, "publisher", rows[0].Name)
+		assert.EqualValues(t, "fasterthanlime", rows[1].Name)
+
+		err = c.Select(conn, &games, builder.NewCond(), hades.Search{}.JoinAssoc(&Game{}, "Studio"))
+		assert.Error(t, err, "must refuse unknown associations")
+
+		type Jam struct {
+			ID    int64
+			Games []*Game `hades:"many_to_many:jam_games"`
+		}
+		err = c.Select(conn, &games, builder.NewCond(), hades.Search{}.JoinAssoc(&Jam{}, "Games"))
+		assert.Error(t, err, "must refuse models unknown to the context")
+
+		_, _, err = hades.Search{}.JoinAssoc(&Game{}, "User").ToSQL(builder.Select("*").From("games"))
+		assert.Error(t, err, "must refuse to build joins without a context")
+	})
+}
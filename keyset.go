@@ -0,0 +1,352 @@
+package hades
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// KeysetColumn is a column results are sorted by, for keyset pagination.
+type KeysetColumn struct {
+	// Field is a field name or a column name
+	Field     string
+	Direction SortDirection
+}
+
+// Keyset describes how SelectPage sorts and splits results into pages.
+// The primary key is always used as the last sort column, so that
+// rows are in a stable order even when other columns have duplicates.
+//
+// Sort columns must not contain NULLs, since rows with NULLs would be
+// skipped: pointer fields are refused, and so are BLOB columns, which
+// cursors can't hold.
+type Keyset struct {
+	Columns []KeysetColumn
+	// Limit is the maximum number of records per page
+	Limit int64
+}
+
+// Page describes where a page returned by SelectPage is in the results.
+type Page struct {
+	// Next is the cursor for the page after this one, if HasNext is set
+	Next    string
+	HasNext bool
+	// Prev is the cursor for the page before this one, if HasPrev is set
+	Prev    string
+	HasPrev bool
+}
+
+type keysetCursor struct {
+	// Backward is set for cursors that fetch the page before a row
+	Backward bool `json:"b,omitempty"`
+	// Values are the sort column values of the row
+	Values []interface{} `json:"v"`
+	// Fingerprint identifies the keyset the cursor was made for
+	Fingerprint string `json:"f"`
+}
+
+type keysetField struct {
+	sf        *StructField
+	column    string
+	direction SortDirection
+}
+
+// SelectPage retrieves a page of records of a model into result, which
+// must be a *[]*Model, sorted according to keyset. Pass an empty cursor to
+// get the first page, then cursors from the returned Page to navigate:
+//
+//   keyset := hades.Keyset{
+//     Columns: []hades.KeysetColumn{{Field: "PublishedAt", Direction: hades.Desc}},
+//     Limit:   20,
+//   }
+//   page, err := c.SelectPage(conn, &games, cond, hades.Search{}, keyset, "")
+//   // later, with the same cond and keyset:
+//   page, err = c.SelectPage(conn, &games, cond, hades.Search{}, keyset, page.Next)
+//
+// Unlike Limit and Offset, pages don't skip or repeat records when rows are
+// inserted or deleted in between, and don't get slower further in.
+//
+// Cursors are opaque strings. They're only valid for the keyset they
+// were returned for. search may have joins, but no ordering, limit or offset.
+func (c *Context) SelectPage(conn *sqlite.Conn, result interface{}, cond builder.Cond, search Search, keyset Keyset, cursor string) (*Page, error) {
+	resultVal := reflect.ValueOf(result)
+	if resultVal.Kind() != reflect.Ptr || resultVal.Elem().Kind() != reflect.Slice {
+		return nil, errors.Errorf("SelectPage expects results to be a *[]*Model, but it got a %v", resultVal.Type())
+	}
+	sliceVal := resultVal.Elem()
+
+	scope, err := c.scopeByType(sliceVal.Type().Elem())
+	if err != nil {
+		return nil, err
+	}
+	ms := scope.GetModelStruct()
+
+	if keyset.Limit <= 0 {
+		return nil, errors.Errorf("SelectPage expects a positive limit, got %d", keyset.Limit)
+	}
+	if len(search.orders) > 0 || search.limit != nil || search.offset != nil {
+		return nil, errors.Errorf("SelectPage can't be used with a search that has ordering, limit or offset")
+	}
+
+	fields, err := c.keysetFields(ms, keyset)
+	if err != nil {
+		return nil, err
+	}
+	fingerprint := keysetFingerprint(fields)
+
+	var cur *keysetCursor
+	if cursor != "" {
+		cur, err = decodeCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		if cur.Fingerprint != fingerprint {
+			return nil, errors.Errorf("cursor was made for a different keyset")
+		}
+		if len(cur.Values) != len(fields) {
+			return nil, errors.Errorf("invalid cursor: expected %d values, got %d", len(fields), len(cur.Values))
+		}
+	}
+	backward := cur != nil && cur.Backward
+
+	if len(search.fields) > 0 {
+		for _, kf := range fields {
+			search = search.Fields(kf.sf.DBName)
+		}
+	}
+	for _, kf := range fields {
+		dir := kf.direction
+		if backward {
+			dir = dir.reverse()
+		}
+		search = search.OrderBy(fmt.Sprintf("%s %s", kf.column, dir))
+	}
+	search = search.Limit(keyset.Limit + 1)
+
+	if cur != nil {
+		cond = builder.And(cond, keysetCond(fields, cur.Values, backward))
+	}
+
+	pageAddr := reflect.New(sliceVal.Type())
+	err = c.Select(conn, pageAddr.Interface(), cond, search)
+	if err != nil {
+		return nil, err
+	}
+	page := pageAddr.Elem()
+
+	more := int64(page.Len()) > keyset.Limit
+	if more {
+		page = page.Slice(0, int(keyset.Limit))
+	}
+	if backward {
+		// we fetched in reverse order
+		swap := reflect.Swapper(page.Interface())
+		for i, j := 0, page.Len()-1; i < j; i, j = i+1, j-1 {
+			swap(i, j)
+		}
+	}
+	sliceVal.Set(page)
+
+	res := &Page{}
+	if backward {
+		res.HasPrev = more
+		res.HasNext = true
+	} else {
+		res.HasNext = more
+		res.HasPrev = cur != nil
+	}
+
+	if page.Len() > 0 {
+		if res.HasNext {
+			res.Next, err = c.encodeCursor(fields, fingerprint, page.Index(page.Len()-1), false)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if res.HasPrev {
+			res.Prev, err = c.encodeCursor(fields, fingerprint, page.Index(0), true)
+			if err != nil {
+				return nil, err
+			}
+		}
+	} else if cur != nil {
+		// we went past the end (or the beginning): the cursor we were
+		// given still points to the other side.
+		flipped := *cur
+		flipped.Backward = !backward
+		token, err := encodeCursor(&flipped)
+		if err != nil {
+			return nil, err
+		}
+		if backward {
+			res.HasNext, res.Next = true, token
+		} else {
+			res.HasPrev, res.Prev = true, token
+		}
+	}
+
+	return res, nil
+}
+
+func (c *Context) keysetFields(ms *ModelStruct, keyset Keyset) ([]keysetField, error) {
+	var fields []keysetField
+	seen := make(map[string]bool)
+
+	add := func(sf *StructField, dir SortDirection) {
+		if seen[sf.DBName] {
+			return
+		}
+		seen[sf.DBName] = true
+		fields = append(fields, keysetField{
+			sf:        sf,
+			column:    fmt.Sprintf("%s.%s", EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName)),
+			direction: dir,
+		})
+	}
+
+	lastDir := Asc
+	for _, kc := range keyset.Columns {
+		sf, err := ms.columnField(kc.Field)
+		if err != nil {
+			return nil, errors.WithMessage(err, "in keyset")
+		}
+		if ms.isSquashed(sf) {
+			return nil, errors.Errorf("keysets can't use %s, a field of a squashed struct of %v", kc.Field, ms.ModelType)
+		}
+		if sf.Struct.Type.Kind() == reflect.Ptr {
+			return nil, errors.Errorf("keysets can't use %s of %v, since it's nullable and rows with NULLs would be skipped", kc.Field, ms.ModelType)
+		}
+		if typ, err := c.sqliteType(sf.Struct.Type); err == nil && strings.EqualFold(typ, "BLOB") {
+			return nil, errors.Errorf("keysets can't use %s of %v, since cursors can't hold blobs", kc.Field, ms.ModelType)
+		}
+		add(sf, kc.Direction)
+		lastDir = kc.Direction
+	}
+
+	if len(ms.PrimaryFields) == 0 {
+		return nil, errors.Errorf("keyset pagination needs a primary key, and %v has none", ms.ModelType)
+	}
+	for _, sf := range ms.PrimaryFields {
+		add(sf, lastDir)
+	}
+	return fields, nil
+}
+
+func keysetFingerprint(fields []keysetField) string {
+	var parts []string
+	for _, kf := range fields {
+		parts = append(parts, fmt.Sprintf("%s %s", kf.sf.DBName, kf.direction))
+	}
+	h := fnv.New32a()
+	h.Write([]byte(strings.Join(parts, ",")))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
+
+// keysetCond returns a condition matching rows after values in the
+// keyset order, or before them if backward is set.
+func keysetCond(fields []keysetField, values []interface{}, backward bool) builder.Cond {
+	op := func(dir SortDirection) string {
+		if (dir == Asc) != backward {
+			return ">"
+		}
+		return "<"
+	}
+
+	sameDirection := true
+	for _, kf := range fields {
+		if kf.direction != fields[0].direction {
+			sameDirection = false
+		}
+	}
+
+	if sameDirection {
+		// (a, b, c) > (?, ?, ?)
+		var columns, placeholders []string
+		for _, kf := range fields {
+			columns = append(columns, kf.column)
+			placeholders = append(placeholders, "?")
+		}
+		if len(fields) == 1 {
+			return builder.Expr(fmt.Sprintf("%s %s ?", columns[0], op(fields[0].direction)), values...)
+		}
+		return builder.Expr(fmt.Sprintf("(%s) %s (%s)",
+			strings.Join(columns, ", "),
+			op(fields[0].direction),
+			strings.Join(placeholders, ", "),
+		), values...)
+	}
+
+	// a > ? OR (a = ? AND b < ?) OR (a = ? AND b = ? AND c > ?)
+	var alternatives []builder.Cond
+	for i, kf := range fields {
+		var conds []builder.Cond
+		for j := 0; j < i; j++ {
+			conds = append(conds, builder.Expr(fmt.Sprintf("%s = ?", fields[j].column), values[j]))
+		}
+		conds = append(conds, builder.Expr(fmt.Sprintf("%s %s ?", kf.column, op(kf.direction)), values[i]))
+		alternatives = append(alternatives, builder.And(conds...))
+	}
+	return builder.Or(alternatives...)
+}
+
+func (c *Context) encodeCursor(fields []keysetField, fingerprint string, rec reflect.Value, backward bool) (string, error) {
+	cur := &keysetCursor{
+		Backward:    backward,
+		Fingerprint: fingerprint,
+	}
+	for _, kf := range fields {
+		field := rec.Elem().FieldByName(kf.sf.Name)
+		value := c.encodeValue(kf.sf, field.Interface())
+		switch value.(type) {
+		case nil, []byte:
+			// a codec may store the field that way
+			return "", errors.Errorf("keysets can't use %s of %v, since it's stored as %#v, which can't be used in cursors", kf.sf.Name, rec.Type().Elem(), value)
+		}
+		cur.Values = append(cur.Values, value)
+	}
+	return encodeCursor(cur)
+}
+
+func encodeCursor(cur *keysetCursor) (string, error) {
+	payload, err := json.Marshal(cur)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return base64.RawURLEncoding.EncodeToString(payload), nil
+}
+
+func decodeCursor(token string) (*keysetCursor, error) {
+	payload, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, errors.Errorf("invalid cursor: %s", err.Error())
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(payload))
+	dec.UseNumber()
+	cur := &keysetCursor{}
+	err = dec.Decode(cur)
+	if err != nil {
+		return nil, errors.Errorf("invalid cursor: %s", err.Error())
+	}
+
+	for i, v := range cur.Values {
+		if n, ok := v.(json.Number); ok {
+			if iv, err := n.Int64(); err == nil {
+				cur.Values[i] = iv
+			} else if fv, err := n.Float64(); err == nil {
+				cur.Values[i] = fv
+			} else {
+				return nil, errors.Errorf("invalid cursor: bad number %s", n)
+			}
+		}
+	}
+	return cur, nil
+}
@@ -0,0 +1,190 @@
+package hades_test
+
+import (
+	"reflect"
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SelectPage(t *testing.T) {
+	type Game struct {
+		ID     int64
+		Title  string
+		Rating int64
+	}
+
+	models := []interface{}{&Game{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		var games []*Game
+		for i := int64(1); i <= 10; i++ {
+			games = append(games, &Game{
+				ID:     i,
+				Title:  string(rune('a' + (i-1)%5)),
+				Rating: i % 3,
+			})
+		}
+		wtest.Must(t, c.Save(conn, games))
+
+		ids := func(games []*Game) []int64 {
+			var res []int64
+			for _, g := range games {
+				res = append(res, g.ID)
+			}
+			return res
+		}
+
+		// same direction everywhere: row-value comparison
+		keyset := hades.Keyset{
+			Columns: []hades.KeysetColumn{{Field: "Title", Direction: hades.Asc}},
+			Limit:   4,
+		}
+		cond := builder.Neq{"id": 9}
+
+		var page []*Game
+		p, err := c.SelectPage(conn, &page, cond, hades.Search{}, keyset, "")
+		wtest.Must(t, err)
+		// titles: 1,6=a 2,7=b 3,8=c 4,(9)=d 5,10=e
+		assert.EqualValues(t, []int64{1, 6, 2, 7}, ids(page))
+		assert.True(t, p.HasNext)
+		assert.False(t, p.HasPrev)
+
+		p, err = c.SelectPage(conn, &page, cond, hades.Search{}, keyset, p.Next)
+		wtest.Must(t, err)
+		assert.EqualValues(t, []int64{3, 8, 4, 5}, ids(page))
+		assert.True(t, p.HasNext)
+		assert.True(t, p.HasPrev)
+		second := p
+
+		p, err = c.SelectPage(conn, &page, cond, hades.Search{}, keyset, p.Next)
+		wtest.Must(t, err)
+		assert.EqualValues(t, []int64{10}, ids(page))
+		assert.False(t, p.HasNext)
+		assert.True(t, p.HasPrev)
+
+		// inserting rows before the cursor doesn't shift pages
+		wtest.Must(t, c.Save(conn, &Game{ID: 11, Title: "a"}))
+
+		p, err = c.SelectPage(conn, &page, cond, hades.Search{}, keyset, p.Prev)
+		wtest.Must(t, err)
+		assert.EqualValues(t, []int64{3, 8, 4, 5}, ids(page))
+		assert.True(t, p.HasNext)
+		assert.True(t, p.HasPrev)
+		assert.EqualValues(t, second.Next, p.Next)
+
+		p, err = c.SelectPage(conn, &page, cond, hades.Search{}, keyset, p.Prev)
+		wtest.Must(t, err)
+		assert.EqualValues(t, []int64{6, 11, 2, 7}, ids(page))
+		assert.True(t, p.HasPrev)
+
+		p, err = c.SelectPage(conn, &page, cond, hades.Search{}, keyset, p.Prev)
+		wtest.Must(t, err)
+		assert.EqualValues(t, []int64{1}, ids(page))
+		assert.False(t, p.HasPrev)
+		assert.True(t, p.HasNext)
+
+		// mixed directions: expanded comparison
+		keyset = hades.Keyset{
+			Columns: []hades.KeysetColumn{
+				{Field: "rating", Direction: hades.Desc},
+				{Field: "Title", Direction: hades.Asc},
+			},
+			Limit: 3,
+		}
+
+		var all []int64
+		cursor := ""
+		for {
+			p, err = c.SelectPage(conn, &page, builder.NewCond(), hades.Search{}.Fields("Title"), keyset, cursor)
+			wtest.Must(t, err)
+			all = append(all, ids(page)...)
+			if !p.HasNext {
+				break
+			}
+			cursor = p.Next
+		}
+		// rating 2: 2(b),5(e),8(c) -> 2,8,5
+		// rating 1: 1(a),4(d),7(b),10(e) -> 1,7,4,10
+		// rating 0: 3(c),6(a),9(d),11(a) -> 6,11,3,9
+		assert.EqualValues(t, []int64{2, 8, 5, 1, 7, 4, 10, 6, 11, 3, 9}, all)
+
+		// cursors are tied to their keyset
+		_, err = c.SelectPage(conn, &page, cond, hades.Search{}, hades.Keyset{Limit: 3}, second.Next)
+		assert.Error(t, err, "must refuse cursors from another keyset")
+
+		_, err = c.SelectPage(conn, &page, cond, hades.Search{}, keyset, "garbage!")
+		assert.Error(t, err, "must refuse invalid cursors")
+
+		_, err = c.SelectPage(conn, &page, cond, hades.Search{}.OrderBy("id"), keyset, "")
+		assert.Error(t, err, "must refuse searches with ordering")
+
+		_, err = c.SelectPage(conn, &page, cond, hades.Search{}, hades.Keyset{
+			Columns: []hades.KeysetColumn{{Field: "Popularity"}},
+			Limit:   3,
+		}, "")
+		assert.Error(t, err, "must refuse unknown fields")
+	})
+}
+
+func Test_SelectPageNullable(t *testing.T) {
+	type Game struct {
+		ID    int64
+		Score *int64
+	}
+
+	models := []interface{}{&Game{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		score := int64(10)
+		wtest.Must(t, c.Save(conn, []*Game{
+			{ID: 1, Score: &score},
+			{ID: 2},
+			{ID: 3, Score: &score},
+		}))
+
+		var page []*Game
+		_, err := c.SelectPage(conn, &page, builder.NewCond(), hades.Search{}, hades.Keyset{
+			Columns: []hades.KeysetColumn{{Field: "Score", Direction: hades.Asc}},
+			Limit:   2,
+		}, "")
+		assert.Error(t, err, "must refuse nullable fields, whose NULLs would be skipped")
+		assert.Contains(t, err.Error(), "nullable")
+	})
+}
+
+func Test_SelectPageBlob(t *testing.T) {
+	type Digest [4]byte
+
+	type Upload struct {
+		ID     int64
+		Digest Digest
+	}
+
+	c, err := hades.NewContext(makeConsumer(t), &Upload{})
+	wtest.Must(t, err)
+	c.RegisterCodec(reflect.TypeOf(Digest{}), hades.Codec{
+		SQLiteType: "BLOB",
+		Encode: func(v interface{}) interface{} {
+			d := v.(Digest)
+			return d[:]
+		},
+		Decode: func(stmt *sqlite.Stmt, col int) (interface{}, error) {
+			var d Digest
+			stmt.ColumnBytes(col, d[:])
+			return d, nil
+		},
+	})
+
+	var page []*Upload
+	_, err = c.SelectPage(nil, &page, builder.NewCond(), hades.Search{}, hades.Keyset{
+		Columns: []hades.KeysetColumn{{Field: "Digest", Direction: hades.Asc}},
+		Limit:   2,
+	}, "")
+	assert.Error(t, err, "must refuse blob columns, which cursors can't hold")
+	assert.Contains(t, err.Error(), "blobs")
+}
@@ -96,6 +96,8 @@ const (
 	TagSettingAssociationForeignKey          TagSetting = "association_foreign_key"
 	TagSettingJoinTableForeignKey            TagSetting = "join_table_foreign_key"
 	TagSettingAssociationJoinTableForeignKey TagSetting = "association_join_table_foreign_key"
+	TagSettingTime                           TagSetting = "time"
+	TagSettingColumn                         TagSetting = "column"
 )
 
 var ValidTagSettings = map[TagSetting]bool{
@@ -107,6 +109,8 @@ var ValidTagSettings = map[TagSetting]bool{
 	TagSettingAssociationForeignKey:          true,
 	TagSettingJoinTableForeignKey:            true,
 	TagSettingAssociationJoinTableForeignKey: true,
+	TagSettingTime:                           true,
+	TagSettingColumn:                         true,
 }
 
 // GetModelStruct get value's model struct, relationships based on struct and tag definition
@@ -128,7 +132,11 @@ func (scope *Scope) GetModelStruct() *ModelStruct {
 	}
 
 	// Get Cached model struct
-	if value := modelStructsMap.Get(reflectType); value != nil {
+	structsMap := modelStructsMap
+	if scope.ctx != nil && scope.ctx.modelStructs != nil {
+		structsMap = scope.ctx.modelStructs
+	}
+	if value := structsMap.Get(reflectType); value != nil {
 		return value
 	}
 
@@ -147,6 +155,19 @@ func (scope *Scope) GetModelStruct() *ModelStruct {
 				TagSettings: parseTagSetting(reflectType, fieldStruct.Name, fieldStruct.Tag),
 			}
 
+			if format, ok := field.TagSettings[TagSettingTime]; ok && !ValidTimeFormats[TimeFormat(format)] {
+				var validFormats []string
+				for vf := range ValidTimeFormats {
+					validFormats = append(validFormats, string(vf))
+				}
+				panic(fmt.Sprintf("invalid time format %q for field %s of type %v - valid time formats are %s",
+					format,
+					fieldStruct.Name,
+					reflectType,
+					strings.Join(validFormats, ", "),
+				))
+			}
+
 			// is ignored field
 			if _, ok := field.TagSettings[TagSettingIgnore]; ok {
 				field.IsIgnored = true
@@ -169,6 +190,9 @@ func (scope *Scope) GetModelStruct() *ModelStruct {
 				if _, isTime := fieldValue.(*time.Time); isTime {
 					// is time
 					field.IsNormal = true
+				} else if scope.ctx.lookupCodec(indirectType) != nil {
+					// has a codec
+					field.IsNormal = true
 				} else {
 					// build relationships
 					switch indirectType.Kind() {
@@ -480,7 +504,11 @@ func (scope *Scope) GetModelStruct() *ModelStruct {
 			}
 
 			// Even it is ignored, also possible to decode db value into the field
-			field.DBName = ToDBName(fieldStruct.Name)
+			if column, ok := field.TagSettings[TagSettingColumn]; ok && column != string(TagSettingColumn) {
+				field.DBName = column
+			} else {
+				field.DBName = ToDBName(fieldStruct.Name)
+			}
 
 			modelStruct.StructFields = append(modelStruct.StructFields, field)
 		}
@@ -493,7 +521,7 @@ func (scope *Scope) GetModelStruct() *ModelStruct {
 		}
 	}
 
-	modelStructsMap.Set(reflectType, &modelStruct)
+	structsMap.Set(reflectType, &modelStruct)
 
 	for _, sf := range modelStruct.StructFields {
 		modelStruct.StructFieldsByName[sf.Name] = sf
@@ -0,0 +1,165 @@
+package hades
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/pkg/errors"
+)
+
+// ExecNamed is like ExecRaw, but query uses named parameters, written
+// :name, @name or $name, instead of positional ones:
+//
+//   query := `SELECT count(*) FROM downloads
+//     WHERE game_id = :game_id AND created_at >= :since`
+//   err := c.ExecNamed(conn, query, map[string]interface{}{
+//     "game_id": 24,
+//     "since":   since,
+//   }, resultFn)
+//
+// params is either a map[string]interface{}, or a struct (or a pointer
+// to one), whose fields are bound by column name, so that for example
+// field GameID is bound to :game_id. Values are converted like DBValue
+// does, and struct fields use their tag settings, like `hades:"time:..."`.
+//
+// The same parameter may appear several times. It's an error for query
+// to use a parameter params doesn't have, or for a map to have one query
+// doesn't use. Structs may have fields query doesn't use, so that models
+// can be passed as params.
+func (c *Context) ExecNamed(conn *sqlite.Conn, query string, params interface{}, resultFn ResultFn) error {
+	positional, names, err := parseNamed(query)
+	if err != nil {
+		return err
+	}
+
+	values, isMap, err := c.namedValues(params)
+	if err != nil {
+		return err
+	}
+
+	var args []interface{}
+	used := make(map[string]bool)
+	for _, name := range names {
+		value, ok := values[name]
+		if !ok {
+			return errors.Errorf("ExecNamed: missing parameter %s", name)
+		}
+		used[name] = true
+		args = append(args, value)
+	}
+
+	var unused []string
+	if isMap {
+		for name := range values {
+			if !used[name] {
+				unused = append(unused, name)
+			}
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		return errors.Errorf("ExecNamed: unused parameters %s", strings.Join(unused, ", "))
+	}
+
+	return c.ExecRaw(conn, positional, resultFn, args...)
+}
+
+// namedValues returns the values of params, a map or a struct, by name,
+// already converted for binding, and whether params is a map.
+func (c *Context) namedValues(params interface{}) (map[string]interface{}, bool, error) {
+	values := make(map[string]interface{})
+
+	if m, ok := params.(map[string]interface{}); ok {
+		for name, value := range m {
+			values[name] = c.DBValue(value)
+		}
+		return values, true, nil
+	}
+
+	val := reflect.ValueOf(params)
+	if val.Kind() == reflect.Ptr && !val.IsNil() {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return nil, false, errors.Errorf("ExecNamed expects params to be a map[string]interface{} or a struct, but it got a %v", reflect.TypeOf(params))
+	}
+
+	ms := c.NewScope(reflect.New(val.Type()).Interface()).GetModelStruct()
+	var addFields func(fields []*StructField, val reflect.Value)
+	addFields = func(fields []*StructField, val reflect.Value) {
+		for _, sf := range fields {
+			field := val.FieldByName(sf.Name)
+			if sf.IsSquashed {
+				addFields(sf.SquashedFields, field)
+				continue
+			}
+			if !sf.IsNormal {
+				continue
+			}
+			values[sf.DBName] = c.encodeValue(sf, field.Interface())
+		}
+	}
+	addFields(ms.StructFields, val)
+	return values, false, nil
+}
+
+// parseNamed replaces the named parameters in query with positional ones,
+// and returns the names in the order they appear. String literals, quoted
+// identifiers and comments are left alone.
+func parseNamed(query string) (string, []string, error) {
+	var out strings.Builder
+	var names []string
+
+	isNameStart := func(b byte) bool {
+		return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z')
+	}
+	isNameChar := func(b byte) bool {
+		return isNameStart(b) || (b >= '0' && b <= '9')
+	}
+
+	// copyUntil copies query[i:] up to and including end,
+	// and returns the index after it.
+	copyUntil := func(i int, end string) int {
+		j := strings.Index(query[i:], end)
+		if j == -1 {
+			out.WriteString(query[i:])
+			return len(query)
+		}
+		j += i + len(end)
+		out.WriteString(query[i:j])
+		return j
+	}
+
+	for i := 0; i < len(query); {
+		b := query[i]
+		switch {
+		case b == '\'' || b == '"' || b == '`':
+			// doubled quotes inside literals are handled as
+			// two consecutive literals, which works out the same.
+			out.WriteByte(b)
+			i = copyUntil(i+1, string(b))
+		case b == '[':
+			i = copyUntil(i, "]")
+		case b == '-' && strings.HasPrefix(query[i:], "--"):
+			i = copyUntil(i, "\n")
+		case b == '/' && strings.HasPrefix(query[i:], "/*"):
+			i = copyUntil(i, "*/")
+		case b == '?':
+			return "", nil, errors.Errorf("ExecNamed: query has a positional parameter at offset %d, use named ones instead", i)
+		case (b == ':' || b == '@' || b == '$') && i+1 < len(query) && isNameStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isNameChar(query[j]) {
+				j++
+			}
+			names = append(names, query[i+1:j])
+			out.WriteByte('?')
+			i = j
+		default:
+			out.WriteByte(b)
+			i++
+		}
+	}
+	return out.String(), names, nil
+}
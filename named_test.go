@@ -0,0 +1,89 @@
+package hades_test
+
+import (
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ExecNamed(t *testing.T) {
+	type Download struct {
+		ID        int64
+		GameID    int64
+		CreatedAt time.Time
+		Note      string
+	}
+
+	models := []interface{}{&Download{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		day := func(d int) time.Time {
+			return time.Date(2018, time.March, d, 12, 0, 0, 0, time.UTC)
+		}
+		wtest.Must(t, c.Save(conn, []*Download{
+			&Download{ID: 1, GameID: 24, CreatedAt: day(1), Note: "is it :game_id?"},
+			&Download{ID: 2, GameID: 24, CreatedAt: day(5)},
+			&Download{ID: 3, GameID: 24, CreatedAt: day(9)},
+			&Download{ID: 4, GameID: 46, CreatedAt: day(9)},
+		}))
+
+		var count int64
+		countFn := func(stmt *sqlite.Stmt) error {
+			count = stmt.ColumnInt64(0)
+			return nil
+		}
+
+		query := `SELECT count(*) FROM downloads
+			WHERE game_id = :game_id -- and not @other
+			AND created_at >= @since AND (note <> ':game_id' OR $game_id = 24)`
+		wtest.Must(t, c.ExecNamed(conn, query, map[string]interface{}{
+			"game_id": 24,
+			"since":   day(4),
+		}, countFn))
+		assert.EqualValues(t, 2, count)
+
+		type filter struct {
+			GameID    int64
+			CreatedAt time.Time
+		}
+		query = "SELECT count(*) FROM downloads WHERE game_id = :game_id AND created_at <= :created_at"
+		wtest.Must(t, c.ExecNamed(conn, query, &filter{GameID: 24, CreatedAt: day(5)}, countFn))
+		assert.EqualValues(t, 2, count)
+
+		wtest.Must(t, c.ExecNamed(conn, query, filter{GameID: 24, CreatedAt: day(1)}, countFn))
+		assert.EqualValues(t, 1, count)
+
+		// structs may have fields the query doesn't use
+		query = "SELECT count(*) FROM downloads WHERE game_id = :game_id"
+		wtest.Must(t, c.ExecNamed(conn, query, Download{GameID: 46}, countFn))
+		assert.EqualValues(t, 1, count)
+
+		wtest.Must(t, c.ExecNamed(conn, "UPDATE downloads SET note = :note WHERE id = :id", map[string]interface{}{
+			"id":   4,
+			"note": "updated",
+		}, nil))
+
+		err := c.ExecNamed(conn, "SELECT count(*) FROM downloads WHERE game_id = :game_id", map[string]interface{}{}, countFn)
+		assert.Error(t, err, "must report missing parameters")
+		assert.Contains(t, err.Error(), "game_id")
+
+		err = c.ExecNamed(conn, "SELECT count(*) FROM downloads WHERE game_id = :game_id", map[string]interface{}{
+			"game_id": 24,
+			"gameid":  46,
+		}, countFn)
+		assert.Error(t, err, "must report unused parameters")
+		assert.Contains(t, err.Error(), "gameid")
+
+		err = c.ExecNamed(conn, "SELECT count(*) FROM downloads WHERE game_id = ?", map[string]interface{}{}, countFn)
+		assert.Error(t, err, "must refuse positional parameters")
+
+		count, err = c.Count(conn, &Download{}, builder.Eq{"note": "updated"})
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, count)
+	})
+}
@@ -1,5 +1,7 @@
 package hades
 
+import "github.com/pkg/errors"
+
 type AssocMode int
 
 const (
@@ -17,12 +19,27 @@ type assocField struct {
 type saveParams struct {
 	assocs   []AssocField
 	omitRoot bool
+	conflict *conflictParams
+}
+
+type insertParams struct {
+	conflict *conflictParams
+}
+
+type conflictParams struct {
+	mode   ConflictMode
+	target []string
+	report *SaveReport
 }
 
 type preloadParams struct {
 	assocs []AssocField
 }
 
+type iterParams struct {
+	reuseRecord bool
+}
+
 type SaveParam interface {
 	ApplyToSaveParams(sp *saveParams)
 }
@@ -31,6 +48,20 @@ type PreloadParam interface {
 	ApplyToPreloadParams(pp *preloadParams)
 }
 
+type IterParam interface {
+	ApplyToIterParams(ip *iterParams)
+}
+
+type InsertParam interface {
+	ApplyToInsertParams(ip *insertParams)
+}
+
+// ConflictParam is a parameter for both Save and Insert.
+type ConflictParam interface {
+	SaveParam
+	InsertParam
+}
+
 type AssocField interface {
 	SaveParam
 	PreloadParam
@@ -54,6 +85,80 @@ func (o *omitRoot) ApplyToSaveParams(sp *saveParams) {
 	sp.omitRoot = true
 }
 
+// OnConflict tells Save and Insert what to do with records that conflict
+// with existing ones. By default, conflicts are checked on primary keys,
+// but another unique constraint can be used by listing the fields
+// (or columns) it's made of:
+//
+//   err := c.Save(conn, games, hades.OnConflict(hades.OnConflictIgnore, "UserID", "Slug"))
+//
+// SQLite refuses targets that don't match a unique constraint. For
+// Save, it applies to records of the model passed, not to associated
+// records of other models.
+func OnConflict(mode ConflictMode, target ...string) ConflictParam {
+	return &onConflict{mode: mode, target: target}
+}
+
+type onConflict struct {
+	mode   ConflictMode
+	target []string
+}
+
+func (o *onConflict) apply(cp **conflictParams) {
+	if *cp == nil {
+		*cp = &conflictParams{}
+	}
+	(*cp).mode = o.mode
+	(*cp).target = o.target
+}
+
+func (o *onConflict) ApplyToSaveParams(sp *saveParams) {
+	o.apply(&sp.conflict)
+}
+
+func (o *onConflict) ApplyToInsertParams(ip *insertParams) {
+	o.apply(&ip.conflict)
+}
+
+// Report tells Save and Insert to add what they did with each record to
+// report: whether it was inserted, updated, or skipped. For Save, only
+// records of the model passed are reported, like for OnConflict.
+func Report(report *SaveReport) ConflictParam {
+	return &reportParam{report: report}
+}
+
+type reportParam struct {
+	report *SaveReport
+}
+
+func (r *reportParam) apply(cp **conflictParams) {
+	if *cp == nil {
+		*cp = &conflictParams{}
+	}
+	(*cp).report = r.report
+}
+
+func (r *reportParam) ApplyToSaveParams(sp *saveParams) {
+	r.apply(&sp.conflict)
+}
+
+func (r *reportParam) ApplyToInsertParams(ip *insertParams) {
+	r.apply(&ip.conflict)
+}
+
+// ReuseRecord tells SelectEach and SelectIter to scan every row into
+// the same record, instead of allocating a new one for each row.
+// Records must not be retained past the callback or loop iteration.
+func ReuseRecord() IterParam {
+	return &reuseRecord{}
+}
+
+type reuseRecord struct{}
+
+func (r *reuseRecord) ApplyToIterParams(ip *iterParams) {
+	ip.reuseRecord = true
+}
+
 // Assoc tells save to save the specified association,
 // but not to remove any existing associated records, even if
 // they're not listed anymore
@@ -107,3 +212,115 @@ func (f *assocField) Children() []AssocField {
 func (f *assocField) Search() Search {
 	return f.search
 }
+
+// Fields tells Save to only write the given fields (by field name
+// or column name) and the primary keys of records. Passed to Save, it
+// applies to the records being saved, and passed to Assoc, to the
+// associated records:
+//
+//   err := c.Save(conn, game, hades.Fields("Title", "UpdatedAt"),
+//     hades.Assoc("Uploads", hades.Fields("Filename")))
+//
+// Records that don't exist yet are inserted with only those columns,
+// so other columns get their default value.
+func Fields(fieldNames ...string) AssocField {
+	return &columnsField{names: fieldNames}
+}
+
+// Omit tells Save to write all fields of records except the given
+// ones. It applies to records like Fields does, and both can be combined.
+func Omit(fieldNames ...string) AssocField {
+	return &columnsField{names: fieldNames, omit: true}
+}
+
+// columnsField restricts the columns Save writes. It's not an actual
+// association, but it's an AssocField so that it can be passed to Assoc.
+type columnsField struct {
+	names []string
+	omit  bool
+}
+
+func (f *columnsField) ApplyToSaveParams(sp *saveParams) {
+	sp.assocs = append(sp.assocs, f)
+}
+
+func (f *columnsField) ApplyToPreloadParams(pp *preloadParams) {
+	pp.assocs = append(pp.assocs, f)
+}
+
+func (f *columnsField) Name() string {
+	return ""
+}
+
+func (f *columnsField) Mode() AssocMode {
+	return AssocModeAppend
+}
+
+func (f *columnsField) Children() []AssocField {
+	return nil
+}
+
+func (f *columnsField) Search() Search {
+	return Search{}
+}
+
+// savedColumns returns the columns of ms that Save writes given
+// fields, or nil if it writes all of them.
+func savedColumns(ms *ModelStruct, fields []*columnsField) (map[string]bool, error) {
+	if len(fields) == 0 {
+		return nil, nil
+	}
+
+	whitelisted := false
+	for _, f := range fields {
+		if !f.omit {
+			whitelisted = true
+		}
+	}
+
+	columns := make(map[string]bool)
+	if !whitelisted {
+		var addAll func(sfs []*StructField)
+		addAll = func(sfs []*StructField) {
+			for _, sf := range sfs {
+				if sf.IsSquashed {
+					addAll(sf.SquashedFields)
+				}
+				if sf.IsNormal {
+					columns[sf.DBName] = true
+				}
+			}
+		}
+		addAll(ms.StructFields)
+	}
+
+	for _, f := range fields {
+		if f.omit {
+			continue
+		}
+		for _, name := range f.names {
+			sf, err := ms.columnField(name)
+			if err != nil {
+				return nil, errors.WithMessage(err, "in Fields")
+			}
+			columns[sf.DBName] = true
+		}
+	}
+
+	for _, f := range fields {
+		if !f.omit {
+			continue
+		}
+		for _, name := range f.names {
+			sf, err := ms.columnField(name)
+			if err != nil {
+				return nil, errors.WithMessage(err, "in Omit")
+			}
+			if sf.IsPrimaryKey {
+				return nil, errors.Errorf("can't omit primary key %s of %v", sf.Name, ms.ModelType)
+			}
+			delete(columns, sf.DBName)
+		}
+	}
+	return columns, nil
+}
@@ -0,0 +1,138 @@
+package hades
+
+import (
+	"fmt"
+	"reflect"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// Pluck retrieves a single column of model for all rows matching cond,
+// into dest, which must be a pointer to a slice, for example:
+//
+//   var gameIDs []int64
+//   err := c.Pluck(conn, &Game{}, "ID", &gameIDs, builder.Eq{"user_id": 12}, hades.Search{})
+//
+// column may be either a field name or a column name. Joins, ordering and
+// limits from search apply.
+func (c *Context) Pluck(conn *sqlite.Conn, model interface{}, column string, dest interface{}, cond builder.Cond, search Search) error {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("Pluck expects dest to be a *[]T, but it got a %v", destVal.Type())
+	}
+	sliceVal := destVal.Elem()
+	elemTyp := sliceVal.Type().Elem()
+
+	ms := c.NewScope(model).GetModelStruct()
+	sf, err := ms.columnField(column)
+	if err != nil {
+		return err
+	}
+	search, err = search.resolveFields(c, ms)
+	if err != nil {
+		return err
+	}
+
+	b := builder.Select(fmt.Sprintf("%s.%s", EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName))).From(ms.TableName).Where(cond)
+	query, args, err := search.ToSQL(b)
+	if err != nil {
+		return err
+	}
+
+	row := 0
+	return c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
+		el := reflect.New(elemTyp).Elem()
+		err := c.scanColumn(stmt, 0, sf, el)
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("For model %s, field %s, scanning row %d", ms.ModelType, sf.Name, row))
+		}
+		row++
+		sliceVal.Set(reflect.Append(sliceVal, el))
+		return nil
+	}, args...)
+}
+
+// Scalar runs query and decodes the first column of the first row into
+// dest, which must be a pointer, and returns whether it did. Like Min and
+// Max, it returns false and leaves dest untouched if there are no rows,
+// or if the value is NULL, as aggregates of no rows are:
+//
+//   var maxID int64
+//   found, err := c.Scalar(conn, "SELECT max(id) FROM games", &maxID)
+//
+func (c *Context) Scalar(conn *sqlite.Conn, query string, dest interface{}, args ...interface{}) (bool, error) {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.IsNil() {
+		return false, errors.Errorf("Scalar expects dest to be a non-nil pointer, but it got a %v", reflect.TypeOf(dest))
+	}
+
+	done, found := false, false
+	err := c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
+		if done {
+			return nil
+		}
+		done = true
+		if stmt.ColumnType(0) == sqlite.SQLITE_NULL {
+			return nil
+		}
+		found = true
+		return c.scanColumn(stmt, 0, nil, destVal.Elem())
+	}, args...)
+	if err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+// scanColumn decodes column col of stmt into dst, checking it first
+// if c.StrictScan is set. sf is used for per-field settings, and may be nil.
+func (c *Context) scanColumn(stmt *sqlite.Stmt, col int, sf *StructField, dst reflect.Value) error {
+	if c.StrictScan {
+		err := c.checkValue(stmt, col, dst.Type())
+		if err != nil {
+			return err
+		}
+	}
+	return c.decodeColumn(stmt, col, sf, dst)
+}
+
+// columnField returns the field of ms stored in column, which
+// may be given as a field name or a column name. Fields of squashed
+// structs are looked up too, see isSquashed.
+func (ms *ModelStruct) columnField(column string) (*StructField, error) {
+	var find func(fields []*StructField) *StructField
+	find = func(fields []*StructField) *StructField {
+		for _, sf := range fields {
+			if sf.IsSquashed {
+				if nsf := find(sf.SquashedFields); nsf != nil {
+					return nsf
+				}
+			}
+			if !sf.IsNormal {
+				continue
+			}
+			if sf.Name == column || sf.DBName == column {
+				return sf
+			}
+		}
+		return nil
+	}
+
+	if sf := find(ms.StructFields); sf != nil {
+		return sf, nil
+	}
+	return nil, errors.Errorf("%v has no column %s", ms.ModelType, column)
+}
+
+// isSquashed returns whether sf is a field of a squashed struct of ms,
+// rather than a field of ms itself.
+func (ms *ModelStruct) isSquashed(sf *StructField) bool {
+	for _, f := range ms.StructFields {
+		if f == sf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,87 @@
+package hades_test
+
+import (
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Pluck(t *testing.T) {
+	type User struct {
+		ID   int64
+		Name string
+	}
+
+	type Game struct {
+		ID          int64
+		Title       string
+		UserID      int64
+		PublishedAt *time.Time
+	}
+
+	models := []interface{}{&User{}, &Game{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		publishedAt := time.Date(2018, time.March, 14, 15, 9, 26, 0, time.UTC)
+		wtest.Must(t, c.Save(conn, []*User{
+			&User{ID: 1, Name: "leafo"},
+			&User{ID: 2, Name: "fasterthanlime"},
+		}))
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 24, Title: "X-Moon", UserID: 1, PublishedAt: &publishedAt},
+			&Game{ID: 46, Title: "butler", UserID: 2},
+			&Game{ID: 48, Title: "itch", UserID: 1},
+		}))
+
+		var ids []int64
+		wtest.Must(t, c.Pluck(conn, &Game{}, "ID", &ids, builder.Eq{"user_id": 1}, hades.Search{}.OrderBy("id DESC")))
+		assert.EqualValues(t, []int64{48, 24}, ids)
+
+		var titles []string
+		wtest.Must(t, c.Pluck(conn, &Game{}, "title", &titles,
+			builder.Eq{"users.name": "leafo"},
+			hades.Search{}.Join("users", "users.id = games.user_id").OrderBy("games.id ASC").Limit(1)))
+		assert.EqualValues(t, []string{"X-Moon"}, titles)
+
+		var dates []*time.Time
+		wtest.Must(t, c.Pluck(conn, &Game{}, "PublishedAt", &dates, builder.NewCond(), hades.Search{}.OrderBy("id ASC")))
+		assert.EqualValues(t, 3, len(dates))
+		assert.True(t, publishedAt.Equal(*dates[0]))
+		assert.Nil(t, dates[1])
+
+		err := c.Pluck(conn, &Game{}, "Developer", &titles, builder.NewCond(), hades.Search{})
+		assert.Error(t, err, "must refuse unknown columns")
+
+		err = c.Pluck(conn, &Game{}, "ID", ids, builder.NewCond(), hades.Search{})
+		assert.Error(t, err, "must refuse non-pointer dest")
+
+		var maxID int64
+		found, err := c.Scalar(conn, "SELECT max(id) FROM games WHERE user_id = ?", &maxID, 1)
+		wtest.Must(t, err)
+		assert.True(t, found)
+		assert.EqualValues(t, 48, maxID)
+
+		maxID = -1
+		found, err = c.Scalar(conn, "SELECT max(id) FROM games WHERE user_id = ?", &maxID, 1000)
+		wtest.Must(t, err)
+		assert.False(t, found, "aggregates of no rows are NULL")
+		assert.EqualValues(t, -1, maxID)
+
+		var title = "untouched"
+		found, err = c.Scalar(conn, "SELECT title FROM games WHERE id = ?", &title, 1000)
+		wtest.Must(t, err)
+		assert.False(t, found)
+		assert.EqualValues(t, "untouched", title)
+
+		var latest time.Time
+		found, err = c.Scalar(conn, "SELECT published_at FROM games WHERE published_at IS NOT NULL", &latest)
+		wtest.Must(t, err)
+		assert.True(t, found)
+		assert.True(t, publishedAt.Equal(latest))
+	})
+}
@@ -40,6 +40,11 @@ func (c *Context) Preload(conn *sqlite.Conn, rec interface{}, opts ...PreloadPar
 	if err != nil {
 		return errors.WithMessage(err, "waking type tree")
 	}
+	for _, ri := range riMap {
+		if ri.columns != nil {
+			return errors.Errorf("Fields and Omit can only be passed to Save")
+		}
+	}
 
 	var walk func(p reflect.Value, pri *RecordInfo) error
 	walk = func(p reflect.Value, pri *RecordInfo) error {
@@ -0,0 +1,317 @@
+package hades
+
+import (
+	"fmt"
+	"reflect"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// Query combines a model, a condition, a search and preloads, so that
+// common reads can be written as a single chain, for example:
+//
+//   var games []*Game
+//   err := c.Query(&Game{}).
+//     Where(builder.Eq{"user_id": 12}).
+//     OrderBy("published_at DESC").
+//     Limit(10).
+//     Preload(hades.Assoc("Uploads")).
+//     All(conn, &games)
+//
+// Like Search, Query is a value: every method returns a modified
+// copy and leaves the original untouched.
+type Query struct {
+	c        *Context
+	scope    *Scope
+	model    interface{}
+	cond     builder.Cond
+	search   Search
+	preloads []PreloadParam
+	err      error
+}
+
+// Query starts a query on model, which must be a registered model,
+// for example &Game{}. Errors are reported by the terminal methods
+// (All, First, Count, etc.)
+func (c *Context) Query(model interface{}) Query {
+	q := Query{
+		c:    c,
+		cond: builder.NewCond(),
+	}
+	q.scope, q.err = c.scopeByType(reflect.TypeOf(model))
+	if q.err == nil {
+		q.model = reflect.New(q.scope.GetModelStruct().ModelType).Interface()
+	}
+	return q
+}
+
+// Where adds a condition. Multiple conditions are combined with AND.
+func (q Query) Where(cond builder.Cond) Query {
+	q.cond = q.cond.And(cond)
+	return q
+}
+
+// Search replaces the query's search with s.
+func (q Query) Search(s Search) Query {
+	q.search = s
+	return q
+}
+
+func (q Query) GroupBy(group string) Query {
+	q.search = q.search.GroupBy(group)
+	return q
+}
+
+func (q Query) OrderBy(order string) Query {
+	q.search = q.search.OrderBy(order)
+	return q
+}
+
+// GroupByField is like Search.GroupByField
+func (q Query) GroupByField(field string) Query {
+	q.search = q.search.GroupByField(field)
+	return q
+}
+
+// OrderByField is like Search.OrderByField
+func (q Query) OrderByField(field string, direction SortDirection) Query {
+	q.search = q.search.OrderByField(field, direction)
+	return q
+}
+
+func (q Query) Limit(limit int64) Query {
+	q.search = q.search.Limit(limit)
+	return q
+}
+
+func (q Query) Offset(offset int64) Query {
+	q.search = q.search.Offset(offset)
+	return q
+}
+
+func (q Query) Join(joinTable string, joinCond string) Query {
+	q.search = q.search.Join(joinTable, joinCond)
+	return q
+}
+
+// Preload specifies associations to load along with records
+// retrieved by All and First.
+func (q Query) Preload(opts ...PreloadParam) Query {
+	q.preloads = append(append([]PreloadParam{}, q.preloads...), opts...)
+	return q
+}
+
+// All retrieves all matching records into dest, which
+// must be a *[]*Model.
+func (q Query) All(conn *sqlite.Conn, dest interface{}) error {
+	if q.err != nil {
+		return q.err
+	}
+
+	err := q.c.Select(conn, dest, q.cond, q.search)
+	if err != nil {
+		return err
+	}
+
+	if len(q.preloads) > 0 {
+		err = q.c.Preload(conn, reflect.ValueOf(dest).Elem().Interface(), q.preloads...)
+		if err != nil {
+			return errors.WithMessage(err, "preloading")
+		}
+	}
+	return nil
+}
+
+// First retrieves the first matching record into dest, which must be
+// a *Model, and returns whether a record was found.
+func (q Query) First(conn *sqlite.Conn, dest interface{}) (bool, error) {
+	if q.err != nil {
+		return false, q.err
+	}
+
+	destVal := reflect.ValueOf(dest)
+	if destVal.Type() != reflect.PtrTo(q.scope.GetModelStruct().ModelType) {
+		return false, errors.Errorf("First expects dest to be a %v, but it got a %v", reflect.PtrTo(q.scope.GetModelStruct().ModelType), destVal.Type())
+	}
+
+	results := reflect.New(reflect.SliceOf(destVal.Type()))
+	err := q.c.Select(conn, results.Interface(), q.cond, q.search.Limit(1))
+	if err != nil {
+		return false, err
+	}
+
+	if results.Elem().Len() == 0 {
+		return false, nil
+	}
+	result := results.Elem().Index(0)
+	destVal.Elem().Set(result.Elem())
+	q.c.moveLoaded(result, destVal)
+
+	if len(q.preloads) > 0 {
+		err = q.c.Preload(conn, dest, q.preloads...)
+		if err != nil {
+			return false, errors.WithMessage(err, "preloading")
+		}
+	}
+	return true, nil
+}
+
+// Count returns the number of matching records. Ordering, limit
+// and offset are ignored.
+func (q Query) Count(conn *sqlite.Conn) (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	return q.c.countWithSearch(conn, q.scope.GetModelStruct(), q.cond, q.search)
+}
+
+// Exists returns whether there is at least one matching record.
+func (q Query) Exists(conn *sqlite.Conn) (bool, error) {
+	if q.err != nil {
+		return false, q.err
+	}
+	return q.c.exists(conn, q.scope.GetModelStruct(), q.cond, q.search)
+}
+
+// Pluck retrieves a single column of all matching records into dest,
+// see Context.Pluck.
+func (q Query) Pluck(conn *sqlite.Conn, column string, dest interface{}) error {
+	if q.err != nil {
+		return q.err
+	}
+	return q.c.Pluck(conn, q.model, column, dest, q.cond, q.search)
+}
+
+// Delete deletes all matching records, and returns how many were
+// deleted. Like Context.Delete, it refuses to run without a condition.
+// Only common table expressions are used from the query's Search: it's
+// an error for it to have joins, ordering, grouping, distinct, fields,
+// limit or offset.
+func (q Query) Delete(conn *sqlite.Conn) (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	err := q.search.checkOnlyCTEs("Delete")
+	if err != nil {
+		return 0, err
+	}
+	if !q.cond.IsValid() {
+		return 0, errors.Errorf("refusing to blindly delete all %v without an explicit builder.Expr(\"1\") clause", q.scope.GetModelStruct().ModelType)
+	}
+	b := builder.Delete(q.cond).From(q.scope.TableName())
+	err = q.c.ExecWithSearch(conn, b, q.search.onlyCTEs(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return int64(conn.Changes()), nil
+}
+
+// Update sets columns of all records matching the query's conditions,
+// and returns how many were changed. updates are as for Context.Update.
+// Like Delete, only common table expressions from Search.With can be
+// used, in conditions. Other clauses of the query's Search are refused.
+func (q Query) Update(conn *sqlite.Conn, updates ...interface{}) (int64, error) {
+	if q.err != nil {
+		return 0, q.err
+	}
+	err := q.search.checkOnlyCTEs("Update")
+	if err != nil {
+		return 0, err
+	}
+	eq, err := q.c.updateEq(q.scope.GetModelStruct(), updates)
+	if err != nil {
+		return 0, err
+	}
+	b := builder.Update(eq).Where(q.cond).Into(q.scope.TableName())
+	err = q.c.ExecWithSearch(conn, b, q.search.onlyCTEs(), nil)
+	if err != nil {
+		return 0, err
+	}
+	return int64(conn.Changes()), nil
+}
+
+// ToSQL returns the query All would run, along with its arguments.
+// Preloads aren't included.
+func (q Query) ToSQL() (string, []interface{}, error) {
+	if q.err != nil {
+		return "", nil, q.err
+	}
+	sel, err := q.c.selectQuery(q.scope.GetModelStruct(), q.cond, q.search)
+	if err != nil {
+		return "", nil, err
+	}
+	return sel.query, sel.args, nil
+}
+
+func (q Query) String() string {
+	query, args, err := q.ToSQL()
+	if err != nil {
+		return fmt.Sprintf("<invalid query: %s>", err.Error())
+	}
+	return fmt.Sprintf("%s %+v", query, args)
+}
+
+// countWithSearch counts records of ms matching cond, with the joins
+// from search. If search groups rows, groups are counted instead.
+func (c *Context) countWithSearch(conn *sqlite.Conn, ms *ModelStruct, cond builder.Cond, search Search) (int64, error) {
+	search, err := search.withoutPaging().resolveFields(c, ms)
+	if err != nil {
+		return 0, err
+	}
+
+	var query string
+	var args []interface{}
+	if search.groupsRows() {
+		// count groups or distinct rows, not matching rows
+		b := builder.Select(fmt.Sprintf("%s.*", EscapeIdentifier(ms.TableName))).From(ms.TableName).Where(cond)
+		query, args, err = search.ToSQL(b)
+		if err != nil {
+			return 0, err
+		}
+		query = fmt.Sprintf("SELECT count(*) FROM (%s)", query)
+	} else {
+		b := builder.Select("count(*)").From(ms.TableName).Where(cond)
+		query, args, err = search.ToSQL(b)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	var result int64
+	err = c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
+		result = stmt.ColumnInt64(0)
+		return nil
+	}, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result, nil
+}
+
+// exists returns whether at least one record of ms matches cond,
+// with the joins and grouping from search.
+func (c *Context) exists(conn *sqlite.Conn, ms *ModelStruct, cond builder.Cond, search Search) (bool, error) {
+	search, err := search.withoutPaging().resolveFields(c, ms)
+	if err != nil {
+		return false, err
+	}
+
+	b := builder.Select("1").From(ms.TableName).Where(cond)
+	query, args, err := search.ToSQL(b)
+	if err != nil {
+		return false, err
+	}
+	query = fmt.Sprintf("SELECT EXISTS (%s)", query)
+
+	var result bool
+	err = c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
+		result = stmt.ColumnInt(0) == 1
+		return nil
+	}, args...)
+	if err != nil {
+		return false, err
+	}
+	return result, nil
+}
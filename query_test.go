@@ -0,0 +1,120 @@
+package hades_test
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Query(t *testing.T) {
+	type User struct {
+		ID   int64
+		Name string
+	}
+
+	type Upload struct {
+		ID     int64
+		GameID int64
+	}
+
+	type Game struct {
+		ID      int64
+		Title   string
+		UserID  int64
+		Uploads []*Upload
+	}
+
+	models := []interface{}{&User{}, &Game{}, &Upload{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, []*User{
+			&User{ID: 1, Name: "leafo"},
+			&User{ID: 2, Name: "amos"},
+		}))
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 1, Title: "X-Moon", UserID: 1, Uploads: []*Upload{&Upload{ID: 10}, &Upload{ID: 11}}},
+			&Game{ID: 2, Title: "Sauerbraten", UserID: 1, Uploads: []*Upload{&Upload{ID: 20}}},
+			&Game{ID: 3, Title: "butler", UserID: 2},
+		}, hades.Assoc("Uploads")))
+
+		base := c.Query(&Game{}).Where(builder.Eq{"user_id": 1})
+
+		var games []*Game
+		wtest.Must(t, base.OrderBy("id DESC").Preload(hades.Assoc("Uploads")).All(conn, &games))
+		assert.EqualValues(t, 2, len(games))
+		assert.EqualValues(t, "Sauerbraten", games[0].Title)
+		assert.EqualValues(t, 1, len(games[0].Uploads))
+		assert.EqualValues(t, 2, len(games[1].Uploads))
+
+		games = nil
+		wtest.Must(t, base.All(conn, &games))
+		assert.EqualValues(t, 2, len(games))
+		assert.Nil(t, games[0].Uploads, "chained methods must not modify the original query")
+
+		g := &Game{}
+		found, err := base.OrderBy("id ASC").Preload(hades.Assoc("Uploads")).First(conn, g)
+		wtest.Must(t, err)
+		assert.True(t, found)
+		assert.EqualValues(t, "X-Moon", g.Title)
+		assert.EqualValues(t, 2, len(g.Uploads))
+
+		found, err = base.Where(builder.Eq{"title": "butler"}).First(conn, g)
+		wtest.Must(t, err)
+		assert.False(t, found)
+
+		count, err := base.Count(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count)
+
+		count, err = c.Query(&Game{}).
+			Join("users", "users.id = games.user_id").
+			Where(builder.Eq{"users.name": "amos"}).
+			Count(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, count)
+
+		count, err = c.Query(&Game{}).GroupBy("user_id").Count(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count, "counts groups when grouping")
+
+		exists, err := base.Where(builder.Like{"title", "Sauer"}).Exists(conn)
+		wtest.Must(t, err)
+		assert.True(t, exists)
+
+		exists, err = base.Where(builder.Eq{"title": "butler"}).Exists(conn)
+		wtest.Must(t, err)
+		assert.False(t, exists)
+
+		var titles []string
+		wtest.Must(t, base.OrderBy("title ASC").Limit(1).Pluck(conn, "Title", &titles))
+		assert.EqualValues(t, []string{"Sauerbraten"}, titles)
+
+		query, args, err := base.OrderBy("id ASC").Limit(5).ToSQL()
+		wtest.Must(t, err)
+		assert.Contains(t, query, `FROM games WHERE user_id=? ORDER BY id ASC LIMIT 5`)
+		assert.EqualValues(t, []interface{}{1}, args)
+
+		_, err = c.Query(&Game{}).Delete(conn)
+		assert.Error(t, err, "must refuse to blindly delete")
+		_, err = base.Limit(1).Delete(conn)
+		assert.Error(t, err, "must refuse limits in delete")
+		_, err = base.OrderBy("id ASC").Delete(conn)
+		assert.Error(t, err, "must refuse ordering in delete")
+		_, err = base.Search(hades.Search{}.Distinct()).Delete(conn)
+		assert.Error(t, err, "must refuse distinct in delete")
+		deleted, err := base.Delete(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, deleted)
+
+		count, err = c.Query(&Game{}).Count(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, count)
+
+		_, err = c.Query(&struct{ ID int64 }{}).Count(conn)
+		assert.Error(t, err, "must refuse unknown models")
+	})
+}
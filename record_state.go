@@ -0,0 +1,129 @@
+package hades
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"weak"
+)
+
+// recordState is what a context remembers about a record it loaded.
+type recordState struct {
+	// columns that were loaded, for records loaded with Search.Fields.
+	// nil if all columns were loaded.
+	partial map[string]bool
+	// values of columns as last loaded or saved, if TrackChanges
+	// is enabled.
+	snapshot map[string]interface{}
+}
+
+// recordStates maps records (*Model pointers) to their state, without
+// keeping them alive: entries are removed once records are collected.
+type recordStates struct {
+	l sync.Mutex
+	m map[weak.Pointer[byte]]*recordState
+}
+
+func newRecordStates() *recordStates {
+	return &recordStates{
+		m: make(map[weak.Pointer[byte]]*recordState),
+	}
+}
+
+func (rs *recordStates) set(rec reflect.Value, state *recordState) {
+	ptr := (*byte)(rec.UnsafePointer())
+	key := weak.Make(ptr)
+
+	rs.l.Lock()
+	defer rs.l.Unlock()
+
+	if _, ok := rs.m[key]; !ok {
+		runtime.AddCleanup(ptr, rs.delete, key)
+	}
+	rs.m[key] = state
+}
+
+func (rs *recordStates) get(rec reflect.Value) *recordState {
+	key := weak.Make((*byte)(rec.UnsafePointer()))
+
+	rs.l.Lock()
+	defer rs.l.Unlock()
+	return rs.m[key]
+}
+
+func (rs *recordStates) forget(rec reflect.Value) {
+	rs.delete(weak.Make((*byte)(rec.UnsafePointer())))
+}
+
+func (rs *recordStates) delete(key weak.Pointer[byte]) {
+	rs.l.Lock()
+	defer rs.l.Unlock()
+	delete(rs.m, key)
+}
+
+// markLoaded records which columns of rec, a *Model, were just
+// loaded. partial is nil if all of them were.
+func (c *Context) markLoaded(rec reflect.Value, partial map[string]bool) {
+	if c.records == nil {
+		// not created with NewContext
+		return
+	}
+	if c.TrackChanges {
+		c.records.set(rec, &recordState{partial: partial})
+		c.snapshot(rec, partial, false)
+		return
+	}
+	if partial == nil {
+		if c.records.len() > 0 {
+			c.records.forget(rec)
+		}
+		return
+	}
+	c.records.set(rec, &recordState{partial: partial})
+}
+
+// moveLoaded makes what c remembers about from, a *Model whose value
+// was just copied to to, apply to to instead.
+func (c *Context) moveLoaded(from reflect.Value, to reflect.Value) {
+	if c.records.len() == 0 {
+		return
+	}
+	state := c.records.get(from)
+	if state == nil {
+		c.records.forget(to)
+		return
+	}
+	c.records.set(to, state)
+	c.records.forget(from)
+}
+
+// loadedColumns returns the columns that were loaded for rec, a *Model,
+// or nil if all of them were (or rec wasn't loaded by c).
+func (c *Context) loadedColumns(rec reflect.Value) map[string]bool {
+	if rec.Kind() != reflect.Ptr || c.records.len() == 0 {
+		return nil
+	}
+	if state := c.records.get(rec); state != nil {
+		return state.partial
+	}
+	return nil
+}
+
+// tracked returns whether c has a snapshot of rec, a *Model.
+func (c *Context) tracked(rec reflect.Value) bool {
+	if rec.Kind() != reflect.Ptr || c.records.len() == 0 {
+		return false
+	}
+	state := c.records.get(rec)
+	return state != nil && state.snapshot != nil
+}
+
+func (rs *recordStates) len() int {
+	if rs == nil {
+		return 0
+	}
+
+	rs.l.Lock()
+	defer rs.l.Unlock()
+	return len(rs.m)
+}
@@ -258,7 +258,11 @@ func (c *Context) Save(conn *sqlite.Conn, rec interface{}, opts ...SaveParam) (e
 
 	for typ, m := range entities {
 		ri := riMap[typ]
-		err := c.saveRows(conn, ri.Field.Mode(), m)
+		var cp *conflictParams
+		if typ == rootRecordInfo.Type {
+			cp = params.conflict
+		}
+		err := c.saveRows(conn, ri.Field.Mode(), ri.columns, cp, m)
 		if err != nil {
 			return errors.WithMessage(err, "saving rows")
 		}
@@ -33,7 +33,7 @@ func (c *Context) saveJoins(conn *sqlite.Conn, mode AssocMode, mtm *ManyToMany)
 		for _, jr := range joinRecs {
 			if jr.Record.IsValid() {
 				// many to many record was specified
-				err := c.Upsert(conn, mtm.Scope, jr.Record)
+				err := c.upsert(conn, mtm.Scope, jr.Record, mtm.columns, nil)
 				if err != nil {
 					return err
 				}
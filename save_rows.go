@@ -8,7 +8,10 @@ import (
 	"github.com/pkg/errors"
 )
 
-func (c *Context) saveRows(conn *sqlite.Conn, mode AssocMode, inputIface interface{}) error {
+// saveRows upserts records of the same model, only writing the given
+// columns if columns isn't nil. Conflicts are handled according to cp,
+// or by updating existing records if it's nil.
+func (c *Context) saveRows(conn *sqlite.Conn, mode AssocMode, columns map[string]bool, cp *conflictParams, inputIface interface{}) error {
 	// inputIFace is a `[]interface{}`
 	input := reflect.ValueOf(inputIface)
 	if input.Kind() != reflect.Slice {
@@ -35,6 +38,9 @@ func (c *Context) saveRows(conn *sqlite.Conn, mode AssocMode, inputIface interfa
 
 	// this will happen for associations
 	if len(primaryFields) != 1 {
+		if cp != nil {
+			return errors.Errorf("OnConflict and Report aren't supported for %s, which has %d primary keys", modelName, len(primaryFields))
+		}
 		if len(primaryFields) != 2 {
 			return errors.Errorf("Have %d primary keys for %s, don't know what to do", len(primaryFields), modelName)
 		}
@@ -97,6 +103,7 @@ func (c *Context) saveRows(conn *sqlite.Conn, mode AssocMode, inputIface interfa
 		if err != nil {
 			return errors.WithMessage(err, "creating ManyToMany relationship")
 		}
+		mtm.columns = columns
 
 		for sourceKey, recs := range valueMap {
 			for _, rec := range recs {
@@ -113,13 +120,23 @@ func (c *Context) saveRows(conn *sqlite.Conn, mode AssocMode, inputIface interfa
 		return nil
 	}
 
-	for i := 0; i < fresh.Len(); i++ {
-		rec := fresh.Index(i)
-		err := c.Upsert(conn, scope, rec)
+	recs := make([]reflect.Value, fresh.Len())
+	for i := range recs {
+		recs[i] = fresh.Index(i)
+	}
+	var cf *conflict
+	if cp != nil {
+		var err error
+		cf, err = cp.resolve(scope.GetModelStruct(), OnConflictUpdate)
 		if err != nil {
-			return errors.WithMessage(err, "upserting DB records")
+			return err
 		}
 	}
 
+	err := c.upsertMany(conn, scope, recs, columns, cf)
+	if err != nil {
+		return errors.WithMessage(err, "upserting DB records")
+	}
+
 	return nil
 }
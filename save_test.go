@@ -1,11 +1,18 @@
 package hades_test
 
 import (
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 
 	"crawshaw.io/sqlite"
+	"crawshaw.io/sqlite/sqliteutil"
+	"github.com/go-xorm/builder"
 	"github.com/itchio/hades"
+	"github.com/itchio/wharf/state"
 	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_Save(t *testing.T) {
@@ -37,3 +44,202 @@ func Test_Save(t *testing.T) {
 		wtest.Must(t, c.Save(conn, p))
 	})
 }
+
+func Test_SaveBulk(t *testing.T) {
+	type Game struct {
+		ID    int64
+		Title string
+	}
+
+	models := []interface{}{
+		&Game{},
+	}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		var numInserts int
+		consumer := c.Consumer
+		c.Consumer = &state.Consumer{
+			OnMessage: func(lvl string, msg string) {
+				if strings.Contains(msg, "INSERT INTO") {
+					numInserts++
+				}
+				consumer.OnMessage(lvl, msg)
+			},
+		}
+
+		var games []*Game
+		for i := 0; i < 1000; i++ {
+			games = append(games, &Game{
+				ID:    int64(i + 1),
+				Title: fmt.Sprintf("Game %d", i+1),
+			})
+		}
+		wtest.Must(t, c.Save(conn, games))
+
+		// 2 columns, so 450 rows per statement,
+		// then the last 100 rows in chunks of 64, 32 and 4
+		assert.EqualValues(t, 5, numInserts)
+		count, err := c.Count(conn, &Game{}, builder.NewCond())
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1000, count)
+
+		numInserts = 0
+		games[0].Title = "Renamed"
+		games = append(games, &Game{ID: 1000, Title: "Last one wins"})
+		wtest.Must(t, c.Save(conn, games))
+		assert.EqualValues(t, 6, numInserts)
+
+		count, err = c.Count(conn, &Game{}, builder.NewCond())
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1000, count)
+
+		g := &Game{}
+		wtest.Must(t, c.Get(conn, g, int64(1)))
+		assert.EqualValues(t, "Renamed", g.Title)
+		wtest.Must(t, c.Get(conn, g, int64(1000)))
+		assert.EqualValues(t, "Last one wins", g.Title)
+	})
+}
+
+func BenchmarkSaveRows(b *testing.B) {
+	type Game struct {
+		ID          int64
+		Title       string
+		Description string
+		Downloads   int64
+	}
+
+	models := []interface{}{
+		&Game{},
+	}
+
+	makeGames := func() []*Game {
+		var games []*Game
+		for i := 0; i < 5000; i++ {
+			games = append(games, &Game{
+				ID:          int64(i + 1),
+				Title:       fmt.Sprintf("Game %d", i+1),
+				Description: "A game",
+				Downloads:   int64(i * 10),
+			})
+		}
+		return games
+	}
+
+	b.Run("bulk", func(b *testing.B) {
+		withBenchContext(b, models, func(conn *sqlite.Conn, c *hades.Context) {
+			games := makeGames()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := c.Save(conn, games)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+
+	b.Run("per-row", func(b *testing.B) {
+		withBenchContext(b, models, func(conn *sqlite.Conn, c *hades.Context) {
+			games := makeGames()
+			scope := c.NewScope(&Game{})
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := func() (err error) {
+					defer sqliteutil.Save(conn)(&err)
+					for _, g := range games {
+						err = c.Upsert(conn, scope, reflect.ValueOf(g))
+						if err != nil {
+							return err
+						}
+					}
+					return nil
+				}()
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	})
+}
+
+func Test_SaveFields(t *testing.T) {
+	type Upload struct {
+		ID       int64
+		GameID   int64
+		Filename string
+		Size     int64
+	}
+
+	type Game struct {
+		ID          int64
+		Title       string
+		Description string
+		Uploads     []*Upload
+	}
+
+	models := []interface{}{
+		&Game{},
+		&Upload{},
+	}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, &Game{
+			ID:          1,
+			Title:       "Original",
+			Description: "Original description",
+			Uploads: []*Upload{
+				{ID: 10, Filename: "game.zip", Size: 100},
+			},
+		}, hades.Assoc("Uploads")))
+
+		load := func() *Game {
+			g := &Game{}
+			wtest.Must(t, c.Get(conn, g, int64(1)))
+			wtest.Must(t, c.Preload(conn, g, hades.Assoc("Uploads")))
+			return g
+		}
+
+		wtest.Must(t, c.Save(conn, &Game{
+			ID:          1,
+			Title:       "New title",
+			Description: "Clobbered",
+			Uploads: []*Upload{
+				{ID: 10, Filename: "new.zip", Size: 0},
+			},
+		}, hades.Fields("Title"), hades.Assoc("Uploads", hades.Fields("filename"))))
+
+		g := load()
+		assert.EqualValues(t, "New title", g.Title)
+		assert.EqualValues(t, "Original description", g.Description)
+		assert.EqualValues(t, "new.zip", g.Uploads[0].Filename)
+		assert.EqualValues(t, 100, g.Uploads[0].Size)
+		// game_id wasn't listed either, but it was already set
+		assert.EqualValues(t, 1, g.Uploads[0].GameID)
+
+		wtest.Must(t, c.Save(conn, &Game{
+			ID:          1,
+			Title:       "Clobbered",
+			Description: "New description",
+		}, hades.Omit("Title")))
+
+		g = load()
+		assert.EqualValues(t, "New title", g.Title)
+		assert.EqualValues(t, "New description", g.Description)
+
+		// new records only get the listed columns
+		wtest.Must(t, c.Save(conn, &Game{
+			ID:          2,
+			Title:       "Second",
+			Description: "Not saved",
+		}, hades.Fields("Title")))
+		g2 := &Game{}
+		wtest.Must(t, c.Get(conn, g2, int64(2)))
+		assert.EqualValues(t, "Second", g2.Title)
+		assert.EqualValues(t, "", g2.Description)
+
+		assert.Error(t, c.Save(conn, g, hades.Omit("ID")))
+		assert.Error(t, c.Save(conn, g, hades.Fields("Nope")))
+		assert.Error(t, c.Preload(conn, g, hades.Assoc("Uploads", hades.Fields("Size"))))
+	})
+}
@@ -1,8 +1,9 @@
 package hades
 
 import (
+	"fmt"
 	"reflect"
-	"time"
+	"strings"
 
 	"crawshaw.io/sqlite"
 	"github.com/pkg/errors"
@@ -43,12 +44,14 @@ func (c *Context) IntoRowsScanner(slicePtr interface{}) ResultFn {
 	recordTemplate := reflect.New(modelTyp).Elem()
 	scope := c.NewScope(recordTemplate.Interface())
 
+	row := 0
 	return func(stmt *sqlite.Stmt) error {
 		recordVal := reflect.New(modelTyp).Elem()
 		err := c.Scan(stmt, scope.GetStructFields(), recordVal)
 		if err != nil {
-			return err
+			return errors.WithMessage(err, fmt.Sprintf("scanning row %d", row))
 		}
+		row++
 		sliceVal.Set(reflect.Append(sliceVal, recordVal))
 		return nil
 	}
@@ -61,15 +64,20 @@ func (c *Context) IntoRowsScanner(slicePtr interface{}) ResultFn {
 //   var g Game
 //   c.Scan(stmt, c.NewScope(), reflect.ValueOf(&g).Elem())
 //
+// If c.StrictScan is set, columns are checked against the fields
+// they're scanned into, see checkColumn.
 func (c *Context) Scan(stmt *sqlite.Stmt, structFields []*StructField, result reflect.Value) error {
 	i := 0
+	modelType := result.Type()
 
-	var processField func(sf *StructField, result reflect.Value) error
-	processField = func(sf *StructField, result reflect.Value) error {
+	// path is the name of the squashed struct sf is in, if any, so that
+	// errors name the field from the model's point of view
+	var processField func(sf *StructField, result reflect.Value, path string) error
+	processField = func(sf *StructField, result reflect.Value, path string) error {
 		field := result.FieldByName(sf.Name)
 		if sf.IsSquashed {
 			for _, nsf := range sf.SquashedFields {
-				err := processField(nsf, field)
+				err := processField(nsf, field, path+sf.Name+".")
 				if err != nil {
 					return err
 				}
@@ -81,71 +89,17 @@ func (c *Context) Scan(stmt *sqlite.Stmt, structFields []*StructField, result re
 			return nil
 		}
 
-		fieldEl := field
-		typ := field.Type()
-		wasPtr := false
-
-		colTyp := stmt.ColumnType(i)
-
-		if typ.Kind() == reflect.Ptr {
-			wasPtr = true
-			if colTyp == sqlite.SQLITE_NULL {
-				field.Set(reflect.Zero(field.Type()))
-				i++
-				return nil
-			}
-
-			fieldEl = field.Elem()
-			typ = typ.Elem()
-		}
-
-		switch typ.Kind() {
-		case reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8, reflect.Int,
-			reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8, reflect.Uint:
-
-			val := stmt.ColumnInt64(i)
-			if wasPtr {
-				field.Set(reflect.ValueOf(&val))
-			} else {
-				fieldEl.SetInt(val)
-			}
-		case reflect.Float64, reflect.Float32:
-			val := stmt.ColumnFloat(i)
-			if wasPtr {
-				field.Set(reflect.ValueOf(&val))
-			} else {
-				fieldEl.SetFloat(val)
-			}
-		case reflect.Bool:
-			val := stmt.ColumnInt(i) == 1
-			if wasPtr {
-				field.Set(reflect.ValueOf(&val))
-			} else {
-				fieldEl.SetBool(val)
-			}
-		case reflect.String:
-			val := stmt.ColumnText(i)
-			if wasPtr {
-				field.Set(reflect.ValueOf(&val))
-			} else {
-				fieldEl.SetString(val)
-			}
-		case reflect.Struct:
-			if typ == reflect.TypeOf(time.Time{}) {
-				text := stmt.ColumnText(i)
-				tim, err := time.Parse(time.RFC3339Nano, text)
-				if err == nil {
-					if wasPtr {
-						field.Set(reflect.ValueOf(&tim))
-					} else {
-						fieldEl.Set(reflect.ValueOf(tim))
-					}
+		err := func() error {
+			if c.StrictScan {
+				err := c.checkColumn(stmt, i, sf, field.Type())
+				if err != nil {
+					return err
 				}
-				break
 			}
-			fallthrough
-		default:
-			return errors.Errorf("For model %s, unknown kind %s for field %s", result.Type(), field.Type().Kind(), sf.Name)
+			return c.decodeColumn(stmt, i, sf, field)
+		}()
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("For model %s, field %s%s", modelType, path, sf.Name))
 		}
 
 		i++
@@ -153,11 +107,97 @@ func (c *Context) Scan(stmt *sqlite.Stmt, structFields []*StructField, result re
 	}
 
 	for _, sf := range structFields {
-		err := processField(sf, result)
+		err := processField(sf, result, "")
 		if err != nil {
 			return err
 		}
 	}
 
+	if c.StrictScan && i != stmt.ColumnCount() {
+		return errors.Errorf("For model %s, expected %d columns but got %d", modelType, i, stmt.ColumnCount())
+	}
+
+	return nil
+}
+
+// checkColumn makes sure column col of stmt is the one for sf, and that
+// it can be decoded into a field of type typ. It's only used in strict mode.
+func (c *Context) checkColumn(stmt *sqlite.Stmt, col int, sf *StructField, typ reflect.Type) error {
+	if col >= stmt.ColumnCount() {
+		return errors.Errorf("expected column %s at index %d, but there are only %d columns", sf.DBName, col, stmt.ColumnCount())
+	}
+
+	name := stmt.ColumnName(col)
+	if !strings.EqualFold(name, sf.DBName) {
+		return errors.Errorf("expected column %s at index %d, got %s", sf.DBName, col, name)
+	}
+
+	return c.checkValue(stmt, col, typ)
+}
+
+// checkValue makes sure the value in column col of stmt can be decoded
+// into a field of type typ without losing information.
+func (c *Context) checkValue(stmt *sqlite.Stmt, col int, typ reflect.Type) error {
+	name := stmt.ColumnName(col)
+	class := stmt.ColumnType(col)
+	if class == sqlite.SQLITE_NULL {
+		if typ.Kind() == reflect.Ptr {
+			return nil
+		}
+		return errors.Errorf("column %s is NULL, but %v is not a pointer", name, typ)
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if c.lookupCodec(typ) != nil {
+		// codecs do their own validation
+		return nil
+	}
+
+	mismatch := func() error {
+		return errors.Errorf("cannot scan %v column %s into %v", class, name, typ)
+	}
+
+	switch typ.Kind() {
+	case reflect.Int64, reflect.Int32, reflect.Int16, reflect.Int8, reflect.Int:
+		if class != sqlite.SQLITE_INTEGER {
+			return mismatch()
+		}
+		n := stmt.ColumnInt64(col)
+		if reflect.Zero(typ).OverflowInt(n) {
+			return errors.Errorf("value %d of column %s overflows %v", n, name, typ)
+		}
+	case reflect.Uint64, reflect.Uint32, reflect.Uint16, reflect.Uint8, reflect.Uint:
+		if class != sqlite.SQLITE_INTEGER {
+			return mismatch()
+		}
+		n := stmt.ColumnInt64(col)
+		if n < 0 || reflect.Zero(typ).OverflowUint(uint64(n)) {
+			return errors.Errorf("value %d of column %s is out of range for %v", n, name, typ)
+		}
+	case reflect.Float64, reflect.Float32:
+		if class != sqlite.SQLITE_FLOAT && class != sqlite.SQLITE_INTEGER {
+			return mismatch()
+		}
+	case reflect.Bool:
+		if class != sqlite.SQLITE_INTEGER {
+			return mismatch()
+		}
+		if n := stmt.ColumnInt64(col); n != 0 && n != 1 {
+			return errors.Errorf("value %d of column %s is not a boolean", n, name)
+		}
+	case reflect.String:
+		if class != sqlite.SQLITE_TEXT {
+			return mismatch()
+		}
+	case reflect.Struct:
+		if typ == timeType {
+			if class == sqlite.SQLITE_BLOB {
+				return mismatch()
+			}
+		}
+	}
 	return nil
 }
@@ -0,0 +1,192 @@
+package hades
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/pkg/errors"
+)
+
+// UnknownColumnMode tells name-based scanners what to do with
+// result columns that don't map to any field.
+type UnknownColumnMode int
+
+const (
+	// UnknownColumnsIgnore skips columns that don't map to any field
+	UnknownColumnsIgnore UnknownColumnMode = iota
+	// UnknownColumnsError makes scanning fail if a column doesn't map to any field
+	UnknownColumnsError
+)
+
+// ScanIntoRowsByName is like ScanIntoRows, except columns are mapped
+// to fields by name rather than by position. See IntoRowsScannerByName.
+func (c *Context) ScanIntoRowsByName(stmt *sqlite.Stmt, slicePtr interface{}, mode UnknownColumnMode) error {
+	return c.IntoRowsScannerByName(slicePtr, mode)(stmt)
+}
+
+// IntoRowsScannerByName returns a ResultFn that scans rows into slicePtr,
+// mapping result columns to fields by name, so it works with queries like:
+//
+//   SELECT * FROM games LEFT JOIN game_embed_data ON ...
+//
+// Columns named "table.column" (usually aliases) are mapped to the
+// squashed model stored in that table. Other columns are mapped to the
+// first field with that name that hasn't been mapped yet, in struct order,
+// so that columns with the same name in several joined tables end up
+// in the right model as long as the tables are selected in the same order
+// as the squashed models.
+//
+// The mapping is computed once per statement.
+func (c *Context) IntoRowsScannerByName(slicePtr interface{}, mode UnknownColumnMode) ResultFn {
+	slicePtrVal := reflect.ValueOf(slicePtr)
+	sliceVal := slicePtrVal.Elem()
+	sliceTyp := sliceVal.Type()
+	if sliceTyp.Kind() != reflect.Slice {
+		err := errors.Errorf("ScanIntoRowsByName expects a slice, got a %v", sliceTyp)
+		return func(stmt *sqlite.Stmt) error {
+			return err
+		}
+	}
+
+	elemTyp := sliceTyp.Elem()
+	modelTyp := elemTyp
+	if modelTyp.Kind() == reflect.Ptr {
+		modelTyp = modelTyp.Elem()
+	}
+	recordTemplate := reflect.New(modelTyp).Elem()
+	scope := c.NewScope(recordTemplate.Interface())
+
+	mappings := make(map[*sqlite.Stmt][]*columnTarget)
+	row := 0
+
+	return func(stmt *sqlite.Stmt) error {
+		targets, ok := mappings[stmt]
+		if !ok {
+			var err error
+			targets, err = c.mapColumns(stmt, scope.GetStructFields(), modelTyp, mode)
+			if err != nil {
+				return err
+			}
+			mappings[stmt] = targets
+		}
+
+		recordPtr := reflect.New(modelTyp)
+		err := c.scanByName(stmt, targets, recordPtr.Elem())
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("scanning row %d", row))
+		}
+		row++
+
+		if elemTyp.Kind() == reflect.Ptr {
+			sliceVal.Set(reflect.Append(sliceVal, recordPtr))
+		} else {
+			sliceVal.Set(reflect.Append(sliceVal, recordPtr.Elem()))
+		}
+		return nil
+	}
+}
+
+// columnTarget is where a result column goes. A nil sf means
+// the column is ignored.
+type columnTarget struct {
+	sf    *StructField
+	path  []string
+	model reflect.Type
+}
+
+type columnCandidate struct {
+	target   *columnTarget
+	table    string
+	assigned bool
+}
+
+func (c *Context) mapColumns(stmt *sqlite.Stmt, structFields []*StructField, modelTyp reflect.Type, mode UnknownColumnMode) ([]*columnTarget, error) {
+	var candidates []*columnCandidate
+
+	var processField func(sf *StructField, path []string, model reflect.Type, table string)
+	processField = func(sf *StructField, path []string, model reflect.Type, table string) {
+		if sf.IsSquashed {
+			nestedTyp := sf.Struct.Type
+			nestedTable := c.NewScope(reflect.Zero(nestedTyp).Interface()).TableName()
+			nestedPath := append(append([]string{}, path...), sf.Name)
+			for _, nsf := range sf.SquashedFields {
+				processField(nsf, nestedPath, nestedTyp, nestedTable)
+			}
+			return
+		}
+
+		if !sf.IsNormal {
+			return
+		}
+
+		candidates = append(candidates, &columnCandidate{
+			target: &columnTarget{
+				sf:    sf,
+				path:  append(append([]string{}, path...), sf.Name),
+				model: model,
+			},
+			table: table,
+		})
+	}
+
+	table := c.NewScope(reflect.Zero(modelTyp).Interface()).TableName()
+	for _, sf := range structFields {
+		processField(sf, nil, modelTyp, table)
+	}
+
+	findCandidate := func(name string) *columnCandidate {
+		table := ""
+		if i := strings.LastIndex(name, "."); i != -1 {
+			table = name[:i]
+			name = name[i+1:]
+		}
+
+		for _, cand := range candidates {
+			if cand.assigned || !strings.EqualFold(cand.target.sf.DBName, name) {
+				continue
+			}
+			if table != "" && !strings.EqualFold(cand.table, table) {
+				continue
+			}
+			return cand
+		}
+		return nil
+	}
+
+	targets := make([]*columnTarget, stmt.ColumnCount())
+	for i := range targets {
+		name := stmt.ColumnName(i)
+		cand := findCandidate(name)
+		if cand == nil {
+			if mode == UnknownColumnsError {
+				return nil, errors.Errorf("For model %s, result column %d (%s) doesn't map to any field", modelTyp, i, name)
+			}
+			targets[i] = &columnTarget{}
+			continue
+		}
+		cand.assigned = true
+		targets[i] = cand.target
+	}
+	return targets, nil
+}
+
+func (c *Context) scanByName(stmt *sqlite.Stmt, targets []*columnTarget, result reflect.Value) error {
+	for i, target := range targets {
+		if target.sf == nil {
+			continue
+		}
+
+		field := result
+		for _, name := range target.path {
+			field = field.FieldByName(name)
+		}
+
+		err := c.scanColumn(stmt, i, target.sf, field)
+		if err != nil {
+			return errors.WithMessage(err, fmt.Sprintf("For model %s, field %s", target.model, target.sf.Name))
+		}
+	}
+	return nil
+}
@@ -68,3 +68,70 @@ func Test_Scan(t *testing.T) {
 		assert.EqualValues(t, 240, rows[1].GameEmbedData.Height)
 	})
 }
+
+func Test_ScanByName(t *testing.T) {
+	type User struct {
+		ID   int64
+		Name string
+	}
+
+	type Game struct {
+		ID     int64
+		Title  string
+		UserID int64
+	}
+
+	models := []interface{}{
+		&User{},
+		&Game{},
+	}
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, []*User{
+			&User{ID: 1, Name: "leafo"},
+			&User{ID: 2, Name: "fasterthanlime"},
+		}))
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 24, Title: "X-Moon", UserID: 1},
+			&Game{ID: 46, Title: "butler", UserID: 2},
+		}))
+
+		type row struct {
+			Game `hades:"squash"`
+			User `hades:"squash"`
+		}
+
+		var rows []row
+		query := "SELECT * FROM games INNER JOIN users ON users.id = games.user_id ORDER BY games.id"
+		wtest.Must(t, c.ExecRaw(conn, query, c.IntoRowsScannerByName(&rows, hades.UnknownColumnsError)))
+		assert.EqualValues(t, 2, len(rows))
+		assert.EqualValues(t, 24, rows[0].Game.ID)
+		assert.EqualValues(t, "X-Moon", rows[0].Title)
+		assert.EqualValues(t, 1, rows[0].User.ID)
+		assert.EqualValues(t, "leafo", rows[0].Name)
+		assert.EqualValues(t, 46, rows[1].Game.ID)
+		assert.EqualValues(t, 2, rows[1].User.ID)
+
+		// table-qualified aliases are mapped regardless of order
+		var aliased []*row
+		query = `SELECT users.id AS "users.id", users.name, games.title, games.id AS "games.id"
+			FROM games INNER JOIN users ON users.id = games.user_id ORDER BY games.id`
+		wtest.Must(t, c.ExecRaw(conn, query, c.IntoRowsScannerByName(&aliased, hades.UnknownColumnsError)))
+		assert.EqualValues(t, 2, len(aliased))
+		assert.EqualValues(t, 24, aliased[0].Game.ID)
+		assert.EqualValues(t, "X-Moon", aliased[0].Title)
+		assert.EqualValues(t, 1, aliased[0].User.ID)
+		assert.EqualValues(t, "leafo", aliased[0].Name)
+
+		var games []Game
+		query = "SELECT title, 'bonus' AS extra, id FROM games ORDER BY id"
+		wtest.Must(t, c.ExecRaw(conn, query, c.IntoRowsScannerByName(&games, hades.UnknownColumnsIgnore)))
+		assert.EqualValues(t, 2, len(games))
+		assert.EqualValues(t, 24, games[0].ID)
+		assert.EqualValues(t, "X-Moon", games[0].Title)
+
+		games = nil
+		err := c.ExecRaw(conn, query, c.IntoRowsScannerByName(&games, hades.UnknownColumnsError))
+		assert.Error(t, err, "must report unknown columns")
+		assert.Contains(t, err.Error(), "extra")
+	})
+}
@@ -16,7 +16,7 @@ func (scope *Scope) IndirectValue() reflect.Value {
 
 // New create a new Scope
 func (scope *Scope) New(value interface{}) *Scope {
-	return &Scope{Value: value}
+	return &Scope{Value: value, ctx: scope.ctx}
 }
 
 // Fields get value's fields
@@ -2,31 +2,207 @@ package hades
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
 )
 
+// SortDirection is the direction in which results are sorted
+type SortDirection int
+
+const (
+	// Asc sorts from lowest to highest
+	Asc SortDirection = iota
+	// Desc sorts from highest to lowest
+	Desc
+)
+
+func (d SortDirection) String() string {
+	if d == Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+// reverse returns the opposite direction
+func (d SortDirection) reverse() SortDirection {
+	if d == Desc {
+		return Asc
+	}
+	return Desc
+}
+
 type join struct {
+	joinType  string
 	joinTable string
-	joinCond  string
+	// either a string or a builder.Cond
+	joinCond interface{}
+	// set for joins added by JoinAssocAs, until they're resolved
+	assoc *joinAssoc
+}
+
+// orderBy is a term of an ORDER BY clause. Terms added with OrderByField
+// name a field, and are resolved to a column by resolveFields.
+type orderBy struct {
+	// raw SQL, like "games.title DESC"
+	raw string
+	// field name or column name
+	field     string
+	direction SortDirection
+	nulls     nullsOrder
+}
+
+type nullsOrder int
+
+const (
+	nullsDefault nullsOrder = iota
+	nullsFirst
+	nullsLast
+)
+
+// groupBy is a term of a GROUP BY clause, either raw
+// or naming a field, like orderBy.
+type groupBy struct {
+	raw   string
+	field string
 }
 
 type Search struct {
-	groups []string
-	orders []string
-	joins  []join
-	offset *int64
-	limit  *int64
+	groups    []groupBy
+	having    []builder.Cond
+	orders    []orderBy
+	joins     []join
+	offset    *int64
+	limit     *int64
+	distinct  bool
+	fields    []string
+	ctes      []cte
+	recursive bool
+	// err is set by methods that can fail, like NullsLast,
+	// and returned by ToSQL
+	err error
 }
 
+// GroupBy groups rows by group, which is raw SQL and must never come
+// from user input. See GroupByField for a safer alternative.
 func (s Search) GroupBy(group string) Search {
-	s.groups = append(s.groups, group)
+	s.groups = append(s.groups, groupBy{raw: group})
 	return s
 }
 
+// GroupByUnsafe is GroupBy, spelled out so that raw SQL
+// stands out in code review.
+func (s Search) GroupByUnsafe(group string) Search {
+	return s.GroupBy(group)
+}
+
+// GroupByField groups rows by a field of the model being queried,
+// given by field name or column name. Unknown fields are refused
+// when the search is used.
+func (s Search) GroupByField(field string) Search {
+	s.groups = append(s.groups, groupBy{field: field})
+	return s
+}
+
+// Having filters groups, see GroupBy. Multiple conditions
+// are combined with AND.
+func (s Search) Having(cond builder.Cond) Search {
+	s.having = append(s.having, cond)
+	return s
+}
+
+// Distinct removes duplicate rows from the results
+func (s Search) Distinct() Search {
+	s.distinct = true
+	return s
+}
+
+// Fields restricts which fields are loaded when selecting models, by
+// field name or column name. Primary keys are always loaded. Other fields
+// are left blank, and saving the records only updates the loaded fields.
+//
+// Fields is ignored by queries that don't select models.
+func (s Search) Fields(names ...string) Search {
+	s.fields = append(s.fields, names...)
+	return s
+}
+
+// With adds a common table expression named name, which the query
+// (including its conditions and joins) can then refer to as a table.
+// name may list column names, like "ranked(game_id, rank)".
+func (s Search) With(name string, sq Subquery) Search {
+	s.ctes = append(s.ctes, cte{name: name, sq: sq})
+	return s
+}
+
+// WithRecursive is like With, but the common table expression may
+// refer to itself, for example to walk a hierarchy:
+//
+//   // collection 12 and all its descendants
+//   tree := hades.RawSubquery("SELECT ?", 12).UnionAll(
+//     c.Subquery(&Collection{}, "ID", builder.NewCond(),
+//       hades.Search{}.Join("tree", "collections.parent_id = tree.id")))
+//   err := c.Select(conn, &collections,
+//     hades.RawSubquery("SELECT id FROM tree").In("collections.id"),
+//     hades.Search{}.WithRecursive("tree(id)", tree))
+//
+// SQLite applies RECURSIVE to the whole WITH clause, so it's
+// enough for one of the expressions to use WithRecursive.
+func (s Search) WithRecursive(name string, sq Subquery) Search {
+	s = s.With(name, sq)
+	s.recursive = true
+	return s
+}
+
+// OrderBy sorts results by order, which is raw SQL, like "id DESC", and
+// must never come from user input. See OrderByField for a safer alternative.
 func (s Search) OrderBy(order string) Search {
-	s.orders = append(s.orders, order)
+	s.orders = append(s.orders, orderBy{raw: order})
+	return s
+}
+
+// OrderByUnsafe is OrderBy, spelled out so that raw SQL
+// stands out in code review.
+func (s Search) OrderByUnsafe(order string) Search {
+	return s.OrderBy(order)
+}
+
+// OrderByField sorts results by a field of the model being queried,
+// given by field name or column name, so it's safe to use with
+// sort parameters coming from users:
+//
+//   hades.Search{}.OrderByField(params.SortBy, hades.Desc)
+//
+// Unknown fields are refused when the search is used.
+func (s Search) OrderByField(field string, direction SortDirection) Search {
+	s.orders = append(s.orders, orderBy{field: field, direction: direction})
+	return s
+}
+
+// NullsLast sorts NULL values after all others, for the
+// last term added with OrderByField.
+func (s Search) NullsLast() Search {
+	return s.setNulls(nullsLast, "NullsLast")
+}
+
+// NullsFirst sorts NULL values before all others, for the
+// last term added with OrderByField.
+func (s Search) NullsFirst() Search {
+	return s.setNulls(nullsFirst, "NullsFirst")
+}
+
+func (s Search) setNulls(nulls nullsOrder, method string) Search {
+	if len(s.orders) == 0 || s.orders[len(s.orders)-1].field == "" {
+		if s.err == nil {
+			s.err = errors.Errorf("%s must follow OrderByField", method)
+		}
+		return s
+	}
+	// don't modify the array shared with the original search
+	s.orders = append([]orderBy(nil), s.orders...)
+	s.orders[len(s.orders)-1].nulls = nulls
 	return s
 }
 
@@ -40,21 +216,220 @@ func (s Search) Offset(offset int64) Search {
 	return s
 }
 
+// Join adds an INNER JOIN with a raw SQL condition
 func (s Search) Join(joinTable string, joinCond string) Search {
+	return s.addJoin("INNER", joinTable, joinCond)
+}
+
+// JoinCond adds an INNER JOIN, whose condition may have arguments
+func (s Search) JoinCond(joinTable string, joinCond builder.Cond) Search {
+	return s.addJoin("INNER", joinTable, joinCond)
+}
+
+// LeftJoin adds a LEFT JOIN, which keeps rows that have no match
+// in joinTable.
+func (s Search) LeftJoin(joinTable string, joinCond builder.Cond) Search {
+	return s.addJoin("LEFT", joinTable, joinCond)
+}
+
+// CrossJoin adds a CROSS JOIN, which matches every row with
+// every row of joinTable.
+func (s Search) CrossJoin(joinTable string) Search {
+	return s.addJoin("CROSS", joinTable, builder.Expr("1"))
+}
+
+// JoinAssoc adds INNER JOINs from model's table to the table of one of
+// its associations, deriving the join conditions from the relationship:
+//
+//   // games along with their developer
+//   hades.Search{}.JoinAssoc(&Game{}, "User")
+//   // collections, with one row per game they contain
+//   hades.Search{}.JoinAssoc(&Collection{}, "Games")
+//
+// many_to_many associations join the join table, then the associated table.
+// Associations are looked up when the search is used with a Context method,
+// like Select or ExecWithSearch, which return an error if assoc isn't valid.
+func (s Search) JoinAssoc(model interface{}, assoc string) Search {
+	return s.JoinAssocAs(model, assoc, "")
+}
+
+// JoinAssocAs is like JoinAssoc, but the associated table is given an
+// alias, so the same table can be joined more than once:
+//
+//   hades.Search{}.
+//     JoinAssocAs(&Game{}, "User", "developer").
+//     JoinAssocAs(&Game{}, "Publisher", "publisher")
+//
+// For many_to_many associations, the join table is aliased to
+// alias followed by an underscore and the join table's name.
+func (s Search) JoinAssocAs(model interface{}, assoc string, alias string) Search {
+	if s.err != nil {
+		return s
+	}
+
+	// the association is looked up by the Context the search is used with
 	s.joins = append(s.joins, join{
+		assoc: &joinAssoc{
+			modelType: reflect.TypeOf(model),
+			name:      assoc,
+			alias:     alias,
+		},
+	})
+	return s
+}
+
+// joinAssoc is a join added by JoinAssocAs, that hasn't been turned into
+// joins yet by resolveJoins.
+type joinAssoc struct {
+	modelType reflect.Type
+	name      string
+	alias     string
+}
+
+// resolveJoins returns a copy of s where the joins added by JoinAssocAs
+// are replaced with joins derived from associations known to c.
+func (s Search) resolveJoins(c *Context) (Search, error) {
+	if s.err != nil {
+		return s, s.err
+	}
+
+	var joins []join
+	for _, j := range s.joins {
+		if j.assoc == nil {
+			joins = append(joins, j)
+			continue
+		}
+
+		scope, err := c.scopeByType(j.assoc.modelType)
+		if err != nil {
+			return s, errors.WithMessage(err, "in JoinAssoc")
+		}
+		ms := scope.GetModelStruct()
+		a, err := c.association(ms, j.assoc.name)
+		if err != nil {
+			return s, errors.WithMessage(err, "in JoinAssoc")
+		}
+
+		toTable, to := a.assoc.TableName, a.assoc.TableName
+		joinTable, jt := a.joinTable, a.joinTable
+		if alias := j.assoc.alias; alias != "" {
+			toTable, to = fmt.Sprintf("%s AS %s", a.assoc.TableName, EscapeIdentifier(alias)), alias
+			if a.joinTable != "" {
+				jt = fmt.Sprintf("%s_%s", alias, a.joinTable)
+				joinTable = fmt.Sprintf("%s AS %s", a.joinTable, EscapeIdentifier(jt))
+			}
+		}
+
+		fromLinks, toLinks := a.links(ms.TableName, jt, to)
+		if a.joinTable != "" {
+			joins = append(joins,
+				join{joinType: "INNER", joinTable: joinTable, joinCond: strings.Join(fromLinks, " AND ")},
+				join{joinType: "INNER", joinTable: toTable, joinCond: strings.Join(toLinks, " AND ")},
+			)
+			continue
+		}
+		joins = append(joins, join{joinType: "INNER", joinTable: toTable, joinCond: strings.Join(fromLinks, " AND ")})
+	}
+	s.joins = joins
+	return s, nil
+}
+
+func (s Search) addJoin(joinType string, joinTable string, joinCond interface{}) Search {
+	s.joins = append(s.joins, join{
+		joinType:  joinType,
 		joinTable: joinTable,
 		joinCond:  joinCond,
 	})
 	return s
 }
 
+// ToSQL applies s to b, then returns the resulting query along with its
+// arguments, in placeholder order: common table expressions, then join
+// conditions, then b's condition, then having conditions.
+func (s Search) ToSQL(b *builder.Builder) (string, []interface{}, error) {
+	if s.err != nil {
+		return "", nil, s.err
+	}
+	for _, j := range s.joins {
+		if j.assoc != nil {
+			return "", nil, errors.Errorf("JoinAssoc(%q) needs to know the models, use it with Context methods like Select", j.assoc.name)
+		}
+	}
+	s.ApplyJoins(b)
+
+	query, args, err := b.ToSQL()
+	if err != nil {
+		return "", nil, errors.WithStack(err)
+	}
+
+	if s.distinct && !strings.HasPrefix(query, "SELECT ") {
+		return "", nil, errors.Errorf("Distinct only applies to SELECT queries, got %s", query)
+	}
+
+	query, havingArgs, err := s.apply(query)
+	if err != nil {
+		return "", nil, err
+	}
+	args = append(args, havingArgs...)
+
+	if len(s.ctes) > 0 {
+		with, withArgs, err := withClause(s.ctes, s.recursive)
+		if err != nil {
+			return "", nil, err
+		}
+		query = with + query
+		args = append(withArgs, args...)
+	}
+	return query, args, nil
+}
+
+// Apply appends s's clauses to sql. It's kept for compatibility: it can't
+// return arguments or errors, and it doesn't apply joins or common table
+// expressions.
+//
+// Deprecated: use ToSQL, which supports all of Search, and returns an
+// error for what it can't do instead of returning incomplete SQL.
 func (s Search) Apply(sql string) string {
+	sql, _, _ = s.apply(sql)
+	return sql
+}
+
+func (s Search) apply(sql string) (string, []interface{}, error) {
+	var args []interface{}
+
+	if s.distinct && strings.HasPrefix(sql, "SELECT ") {
+		sql = "SELECT DISTINCT " + strings.TrimPrefix(sql, "SELECT ")
+	}
+
 	if len(s.groups) > 0 {
-		sql = fmt.Sprintf("%s GROUP BY %s", sql, strings.Join(s.groups, ", "))
+		var groups []string
+		for _, g := range s.groups {
+			if g.field != "" {
+				return "", nil, errors.Errorf("GroupByField(%q) needs to know the model, use it with Context methods like Select", g.field)
+			}
+			groups = append(groups, g.raw)
+		}
+		sql = fmt.Sprintf("%s GROUP BY %s", sql, strings.Join(groups, ", "))
+	}
+
+	if len(s.having) > 0 {
+		havingSQL, havingArgs, err := builder.ToSQL(builder.And(s.having...))
+		if err != nil {
+			return "", nil, errors.WithStack(err)
+		}
+		sql = fmt.Sprintf("%s HAVING %s", sql, havingSQL)
+		args = append(args, havingArgs...)
 	}
 
 	if len(s.orders) > 0 {
-		sql = fmt.Sprintf("%s ORDER BY %s", sql, strings.Join(s.orders, ", "))
+		var orders []string
+		for _, o := range s.orders {
+			if o.field != "" {
+				return "", nil, errors.Errorf("OrderByField(%q) needs to know the model, use it with Context methods like Select", o.field)
+			}
+			orders = append(orders, o.raw)
+		}
+		sql = fmt.Sprintf("%s ORDER BY %s", sql, strings.Join(orders, ", "))
 	}
 
 	if s.limit != nil || s.offset != nil {
@@ -73,15 +448,137 @@ func (s Search) Apply(sql string) string {
 		}
 	}
 
-	return sql
+	return sql, args, nil
 }
 
+// ApplyJoins adds s's joins to b. Joins added by JoinAssoc are skipped,
+// since they need a Context to be resolved: ToSQL reports them instead.
 func (s Search) ApplyJoins(b *builder.Builder) {
 	for _, j := range s.joins {
-		b.InnerJoin(j.joinTable, j.joinCond)
+		if j.assoc != nil {
+			continue
+		}
+		b.Join(j.joinType, j.joinTable, j.joinCond)
 	}
 }
 
+// resolveFields returns a copy of s where the fields named by OrderByField
+// and GroupByField are replaced with qualified, escaped columns of ms, and
+// joins added by JoinAssoc are resolved against c.
+func (s Search) resolveFields(c *Context, ms *ModelStruct) (Search, error) {
+	s, err := s.resolveJoins(c)
+	if err != nil {
+		return s, err
+	}
+
+	column := func(field string) (string, error) {
+		sf, err := ms.columnField(field)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s.%s", EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName)), nil
+	}
+
+	orders := make([]orderBy, len(s.orders))
+	for i, o := range s.orders {
+		if o.field == "" {
+			orders[i] = o
+			continue
+		}
+
+		col, err := column(o.field)
+		if err != nil {
+			return s, errors.WithMessage(err, "in OrderByField")
+		}
+		raw := fmt.Sprintf("%s %s", col, o.direction)
+		switch o.nulls {
+		// false sorts before true
+		case nullsLast:
+			raw = fmt.Sprintf("%s IS NULL, %s", col, raw)
+		case nullsFirst:
+			raw = fmt.Sprintf("%s IS NOT NULL, %s", col, raw)
+		}
+		orders[i] = orderBy{raw: raw}
+	}
+	s.orders = orders
+
+	groups := make([]groupBy, len(s.groups))
+	for i, g := range s.groups {
+		if g.field == "" {
+			groups[i] = g
+			continue
+		}
+
+		col, err := column(g.field)
+		if err != nil {
+			return s, errors.WithMessage(err, "in GroupByField")
+		}
+		groups[i] = groupBy{raw: col}
+	}
+	s.groups = groups
+
+	return s, nil
+}
+
+// withoutPaging returns a copy of s without ordering, limit or offset,
+// for queries that look at the whole result set.
+func (s Search) withoutPaging() Search {
+	s.orders = nil
+	s.limit = nil
+	s.offset = nil
+	return s
+}
+
+// onlyCTEs returns a copy of s with only its common table
+// expressions, for UPDATE and DELETE queries.
+func (s Search) onlyCTEs() Search {
+	return Search{ctes: s.ctes, recursive: s.recursive, err: s.err}
+}
+
+// optionalSearch returns the search passed to op as an optional
+// argument, or an empty Search if there's none.
+func optionalSearch(op string, search []Search) (Search, error) {
+	switch len(search) {
+	case 0:
+		return Search{}, nil
+	case 1:
+		return search[0], nil
+	}
+	return Search{}, errors.Errorf("%s takes at most one Search, got %d", op, len(search))
+}
+
+// checkOnlyCTEs returns an error if s has clauses other than common
+// table expressions, which op (an UPDATE or DELETE query) would ignore.
+func (s Search) checkOnlyCTEs(op string) error {
+	if s.err != nil {
+		return s.err
+	}
+	if len(s.joins) > 0 || len(s.orders) > 0 || len(s.groups) > 0 || len(s.having) > 0 ||
+		s.distinct || len(s.fields) > 0 || s.limit != nil || s.offset != nil {
+		return errors.Errorf("%s only supports common table expressions, not joins, ordering, grouping, distinct, fields, limit or offset", op)
+	}
+	return nil
+}
+
+// groupsRows returns true if s changes which rows are returned
+// beyond filtering them.
+func (s Search) groupsRows() bool {
+	return len(s.groups) > 0 || len(s.having) > 0 || s.distinct
+}
+
+// String returns s's clauses for debugging, followed by the arguments
+// of its Having conditions, if any. Errors are rendered in place of the
+// clauses instead of panicking.
 func (s Search) String() string {
-	return s.Apply("")
+	if s.err != nil {
+		return fmt.Sprintf("!(hades.Search error: %v)", s.err)
+	}
+	sql, args, err := s.apply("")
+	if err != nil {
+		return fmt.Sprintf("!(hades.Search error: %v)", err)
+	}
+	if len(args) > 0 {
+		sql = fmt.Sprintf("%s %v", sql, args)
+	}
+	return sql
 }
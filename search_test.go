@@ -3,6 +3,7 @@ package hades
 import (
 	"testing"
 
+	"github.com/go-xorm/builder"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -14,4 +15,93 @@ func Test_Search(t *testing.T) {
 	assert.EqualValues(t, "x ORDER BY id desc", Search{}.OrderBy("id desc").Apply("x"))
 	assert.EqualValues(t, "x ORDER BY id asc", Search{}.OrderBy("id asc").Apply("x"))
 	assert.EqualValues(t, "x ORDER BY id asc, created_at desc", Search{}.OrderBy("id asc").OrderBy("created_at desc").Apply("x"))
+
+	assert.EqualValues(t, "x GROUP BY a HAVING (count(*) > 1)", Search{}.GroupBy("a").Having(builder.Expr("count(*) > 1")).Apply("x"))
+
+	withArgs := Search{}.GroupBy("a").Having(builder.Expr("count(*) > ?", 1))
+	assert.EqualValues(t, " GROUP BY a HAVING (count(*) > ?) [1]", withArgs.String())
+	query, args, err := withArgs.ToSQL(builder.Select("a").From("x"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "SELECT a FROM x GROUP BY a HAVING (count(*) > ?)", query)
+	assert.EqualValues(t, []interface{}{1}, args, "ToSQL must keep Having arguments")
+
+	unresolved := Search{}.OrderByField("Title", Asc)
+	assert.NotPanics(t, func() { unresolved.Apply("x") })
+	assert.Contains(t, unresolved.String(), "OrderByField")
+	_, _, err = unresolved.ToSQL(builder.Select("a").From("x"))
+	assert.Error(t, err, "ToSQL must report unresolved fields")
+
+	joined := Search{}.JoinAssoc(struct{}{}, "Nope")
+	assert.NotPanics(t, func() { joined.ApplyJoins(builder.Select("a").From("x")) })
+}
+
+func Test_SearchToSQL(t *testing.T) {
+	s := Search{}.
+		LeftJoin("uploads", builder.And(builder.Expr("uploads.game_id = games.id"), builder.Eq{"uploads.type": "html"})).
+		GroupBy("games.id").
+		Having(builder.Gte{"count(uploads.id)": 3}).
+		OrderBy("games.id").
+		Limit(10)
+
+	query, args, err := s.ToSQL(builder.Select("games.*").From("games").Where(builder.Eq{"games.user_id": 12}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "SELECT games.* FROM games LEFT JOIN uploads ON (uploads.game_id = games.id) AND uploads.type=? WHERE games.user_id=? GROUP BY games.id HAVING count(uploads.id)>=? ORDER BY games.id LIMIT 10", query)
+	assert.EqualValues(t, []interface{}{"html", 12, 3}, args, "args must be in placeholder order")
+
+	query, _, err = Search{}.Distinct().CrossJoin("users").ToSQL(builder.Select("games.title").From("games"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "SELECT DISTINCT games.title FROM games CROSS JOIN users ON 1", query)
+
+	_, _, err = Search{}.Distinct().ToSQL(builder.Delete(builder.Eq{"id": 1}).From("games"))
+	assert.Error(t, err, "Distinct must only apply to SELECT")
+}
+
+func Test_SearchWith(t *testing.T) {
+	s := Search{}.
+		With("recent", RawSubquery("SELECT id FROM games WHERE published_at > ?", "2018-01-01")).
+		JoinCond("recent", builder.Expr("recent.id = games.id AND games.user_id <> ?", 3)).
+		Having(builder.Gt{"count(*)": 1}).
+		GroupBy("games.user_id")
+
+	query, args, err := s.ToSQL(builder.Select("games.user_id").From("games").Where(builder.Eq{"games.title": "x"}))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "WITH recent AS (SELECT id FROM games WHERE published_at > ?) SELECT games.user_id FROM games INNER JOIN recent ON recent.id = games.id AND games.user_id <> ? WHERE games.title=? GROUP BY games.user_id HAVING count(*)>?", query)
+	assert.EqualValues(t, []interface{}{"2018-01-01", 3, "x", 1}, args, "args must be in placeholder order")
+}
+
+func Test_SearchFieldOrders(t *testing.T) {
+	type Game struct {
+		ID          int64
+		Title       string
+		UserID      int64
+		PublishedAt *string
+	}
+	ms := (&Scope{Value: &Game{}}).GetModelStruct()
+
+	s, err := Search{}.
+		GroupByField("UserID").
+		OrderByField("PublishedAt", Desc).NullsLast().
+		OrderBy("count(*) DESC").
+		OrderByField("title", Asc).
+		resolveFields(nil, ms)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "x GROUP BY games.user_id ORDER BY games.published_at IS NULL, games.published_at DESC, count(*) DESC, games.title ASC", s.Apply("x"))
+
+	_, err = Search{}.OrderByField("title; DROP TABLE games", Asc).resolveFields(nil, ms)
+	assert.Error(t, err, "must refuse unknown fields")
+
+	_, err = Search{}.GroupByField("Developer").resolveFields(nil, ms)
+	assert.Error(t, err, "must refuse unknown fields")
+
+	_, err = Search{}.OrderBy("id").NullsFirst().resolveFields(nil, ms)
+	assert.Error(t, err, "NullsFirst must follow OrderByField")
+
+	_, _, err = Search{}.OrderByField("Title", Asc).ToSQL(builder.Select("*").From("games"))
+	assert.Error(t, err, "unresolved fields must be refused")
+
+	base := Search{}.OrderByField("Title", Asc)
+	_ = base.NullsLast()
+	s, err = base.resolveFields(nil, ms)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "x ORDER BY games.title ASC", s.Apply("x"), "NullsLast must not modify the original search")
 }
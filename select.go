@@ -29,26 +29,22 @@ func (c *Context) Select(conn *sqlite.Conn, result interface{}, cond builder.Con
 	}
 
 	ms := scope.GetModelStruct()
-	columns, fields := c.selectFields(ms)
-
-	b := builder.Select(columns...).From(ms.TableName).Where(cond)
-	search.ApplyJoins(b)
-
-	query, args, err := b.ToSQL()
+	sel, err := c.selectQuery(ms, cond, search)
 	if err != nil {
 		return err
 	}
-	query = search.Apply(query)
 
-	return c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
+	row := 0
+	return c.ExecRaw(conn, sel.query, func(stmt *sqlite.Stmt) error {
 		el := reflect.New(ms.ModelType)
-		err := c.Scan(stmt, fields, el.Elem())
+		err := c.scanSelected(stmt, sel, el)
 		if err != nil {
-			return err
+			return errors.WithMessage(err, fmt.Sprintf("scanning row %d", row))
 		}
+		row++
 		resultVal.Set(reflect.Append(resultVal, el))
 		return nil
-	}, args...)
+	}, sel.args...)
 }
 
 //
@@ -72,23 +68,88 @@ func (c *Context) SelectOne(conn *sqlite.Conn, result interface{}, cond builder.
 	ms := scope.GetModelStruct()
 	columns, fields := c.selectFields(ms)
 
-	query, args, err := builder.Select(columns...).From(ms.TableName).Where(cond).ToSQL()
+	query, args, err := Search{}.Limit(1).ToSQL(builder.Select(columns...).From(ms.TableName).Where(cond))
 	if err != nil {
 		return found, err
 	}
-	query = Search{}.Limit(1).Apply(query)
 
 	err = c.ExecRaw(conn, query, func(stmt *sqlite.Stmt) error {
 		err := c.Scan(stmt, fields, resultVal)
 		if err != nil {
-			return err
+			return errors.WithMessage(err, "scanning row 0")
 		}
+		c.markLoaded(resultVal.Addr(), nil)
 		found = true
 		return nil
 	}, args...)
 	return found, err
 }
 
+// selection is a query that selects records of a model,
+// along with what's needed to scan them.
+type selection struct {
+	query  string
+	args   []interface{}
+	fields []*StructField
+	// partial lists the columns selected, if not all of them are
+	partial map[string]bool
+}
+
+// selectQuery returns a query that selects columns of ms (all of them,
+// unless search specifies Fields)
+func (c *Context) selectQuery(ms *ModelStruct, cond builder.Cond, search Search) (*selection, error) {
+	sel := &selection{}
+
+	search, err := search.resolveFields(c, ms)
+	if err != nil {
+		return nil, err
+	}
+
+	var columns []string
+	if len(search.fields) > 0 {
+		sel.partial = make(map[string]bool)
+		for _, sf := range ms.PrimaryFields {
+			sel.partial[sf.DBName] = true
+		}
+		for _, name := range search.fields {
+			sf, err := ms.columnField(name)
+			if err != nil {
+				return nil, errors.WithMessage(err, "in Search.Fields")
+			}
+			if ms.isSquashed(sf) {
+				return nil, errors.Errorf("Search.Fields can't select %s, a field of a squashed struct of %v", name, ms.ModelType)
+			}
+			sel.partial[sf.DBName] = true
+		}
+
+		for _, sf := range ms.StructFields {
+			if sf.IsNormal && sel.partial[sf.DBName] {
+				columns = append(columns, fmt.Sprintf(`%s.%s`, EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName)))
+				sel.fields = append(sel.fields, sf)
+			}
+		}
+	} else {
+		columns, sel.fields = c.selectFields(ms)
+	}
+
+	b := builder.Select(columns...).From(ms.TableName).Where(cond)
+	sel.query, sel.args, err = search.ToSQL(b)
+	if err != nil {
+		return nil, err
+	}
+	return sel, nil
+}
+
+// scanSelected scans a row of sel into rec, a *Model
+func (c *Context) scanSelected(stmt *sqlite.Stmt, sel *selection, rec reflect.Value) error {
+	err := c.Scan(stmt, sel.fields, rec.Elem())
+	if err != nil {
+		return err
+	}
+	c.markLoaded(rec, sel.partial)
+	return nil
+}
+
 func (c *Context) selectFields(ms *ModelStruct) ([]string, []*StructField) {
 	var columns []string
 	var fields []*StructField
@@ -0,0 +1,62 @@
+package hades
+
+import (
+	"reflect"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// SelectInto runs query and appends the resulting rows to dest, which
+// must be a *[]T or a *[]*T, where T is any struct - it doesn't need to
+// be a registered model. It's meant for reports and other queries whose
+// results don't have the shape of a model:
+//
+//   type gameStats struct {
+//     GameID    int64
+//     Downloads int64 `hades:"column:download_count"`
+//   }
+//   var stats []gameStats
+//   err := c.SelectInto(conn, &stats,
+//     builder.Select("game_id", "count(*) AS download_count").From("downloads").GroupBy("game_id"))
+//
+// query is either a *builder.Builder or raw SQL, in which case args
+// are its arguments. Columns are mapped to fields by name, like
+// IntoRowsScannerByName does, so squashed structs are supported, and
+// result columns that don't map to any field are an error.
+//
+// T's layout is computed once and cached, but T isn't registered
+// as a model, so AutoMigrate doesn't create a table for it.
+func (c *Context) SelectInto(conn *sqlite.Conn, dest interface{}, query interface{}, args ...interface{}) error {
+	destVal := reflect.ValueOf(dest)
+	if destVal.Kind() != reflect.Ptr || destVal.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("SelectInto expects dest to be a *[]T, but it got a %v", reflect.TypeOf(dest))
+	}
+	elemTyp := destVal.Elem().Type().Elem()
+	if elemTyp.Kind() == reflect.Ptr {
+		elemTyp = elemTyp.Elem()
+	}
+	if elemTyp.Kind() != reflect.Struct {
+		return errors.Errorf("SelectInto expects dest to be a slice of structs, but it got a %v", destVal.Type())
+	}
+
+	var sql string
+	switch q := query.(type) {
+	case *builder.Builder:
+		if len(args) > 0 {
+			return errors.Errorf("SelectInto takes no args with a *builder.Builder, they're part of the builder")
+		}
+		var err error
+		sql, args, err = q.ToSQL()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	case string:
+		sql = q
+	default:
+		return errors.Errorf("SelectInto expects query to be a *builder.Builder or a string, but it got a %v", reflect.TypeOf(query))
+	}
+
+	return c.ExecRaw(conn, sql, c.IntoRowsScannerByName(dest, UnknownColumnsError), args...)
+}
@@ -0,0 +1,87 @@
+package hades_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SelectInto(t *testing.T) {
+	type Game struct {
+		ID    int64
+		Title string
+	}
+
+	type Download struct {
+		ID        int64
+		GameID    int64
+		CreatedAt time.Time
+	}
+
+	models := []interface{}{&Game{}, &Download{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		day := func(d int) time.Time {
+			return time.Date(2018, time.March, d, 12, 0, 0, 0, time.UTC)
+		}
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 24, Title: "X-Moon"},
+			&Game{ID: 46, Title: "butler"},
+		}))
+		wtest.Must(t, c.Save(conn, []*Download{
+			&Download{ID: 1, GameID: 24, CreatedAt: day(1)},
+			&Download{ID: 2, GameID: 24, CreatedAt: day(5)},
+			&Download{ID: 3, GameID: 46, CreatedAt: day(3)},
+		}))
+
+		type gameStats struct {
+			GameID    int64
+			Downloads int64     `hades:"column:download_count"`
+			Latest    time.Time `hades:"column:latest"`
+		}
+
+		var stats []gameStats
+		wtest.Must(t, c.SelectInto(conn, &stats,
+			builder.Select("game_id", "count(*) AS download_count", "max(created_at) AS latest").
+				From("downloads").
+				Where(builder.Gte{"created_at": c.DBValue(day(1))}).
+				GroupBy("game_id").
+				OrderBy("game_id ASC")))
+		assert.EqualValues(t, 2, len(stats))
+		assert.EqualValues(t, 24, stats[0].GameID)
+		assert.EqualValues(t, 2, stats[0].Downloads)
+		assert.True(t, day(5).Equal(stats[0].Latest))
+		assert.EqualValues(t, 1, stats[1].Downloads)
+
+		type titled struct {
+			Game      `hades:"squash"`
+			Downloads int64
+		}
+		var rows []*titled
+		wtest.Must(t, c.SelectInto(conn, &rows, `SELECT games.*, count(downloads.id) AS downloads
+			FROM games LEFT JOIN downloads ON downloads.game_id = games.id
+			WHERE games.title <> ? GROUP BY games.id ORDER BY games.id`, "nope"))
+		assert.EqualValues(t, 2, len(rows))
+		assert.EqualValues(t, "X-Moon", rows[0].Title)
+		assert.EqualValues(t, 2, rows[0].Downloads)
+		assert.EqualValues(t, 46, rows[1].ID)
+
+		assert.Nil(t, c.ScopeMap.ByType(reflect.TypeOf(&gameStats{})), "DTOs must not be registered as models")
+
+		stats = nil
+		err := c.SelectInto(conn, &stats, "SELECT game_id, 1 AS extra FROM downloads")
+		assert.Error(t, err, "must report columns that don't map to any field")
+
+		err = c.SelectInto(conn, &stats, builder.Select("game_id").From("downloads"), 1)
+		assert.Error(t, err, "must refuse args with a builder")
+
+		err = c.SelectInto(conn, stats, "SELECT game_id FROM downloads")
+		assert.Error(t, err, "must refuse non-pointer dest")
+	})
+}
@@ -3,6 +3,7 @@ package hades_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"crawshaw.io/sqlite"
 	"github.com/go-xorm/builder"
@@ -165,3 +166,148 @@ func Test_SelectSquashed(t *testing.T) {
 	assert.True(t, found)
 	assert.EqualValues(t, baseAndroids[0], *a)
 }
+
+func Test_SelectSearchClauses(t *testing.T) {
+	type Upload struct {
+		ID     int64
+		GameID int64
+	}
+
+	type Game struct {
+		ID    int64
+		Title string
+	}
+
+	type Collection struct {
+		ID    int64
+		Title string
+	}
+
+	type CollectionGame struct {
+		CollectionID int64 `hades:"primary_key"`
+		GameID       int64 `hades:"primary_key"`
+	}
+
+	models := []interface{}{&Upload{}, &Game{}, &Collection{}, &CollectionGame{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 1, Title: "Three uploads"},
+			&Game{ID: 2, Title: "One upload"},
+			&Game{ID: 3, Title: "Four uploads"},
+		}))
+		wtest.Must(t, c.Save(conn, []*Upload{
+			&Upload{ID: 1, GameID: 1}, &Upload{ID: 2, GameID: 1}, &Upload{ID: 3, GameID: 1},
+			&Upload{ID: 4, GameID: 2},
+			&Upload{ID: 5, GameID: 3}, &Upload{ID: 6, GameID: 3}, &Upload{ID: 7, GameID: 3}, &Upload{ID: 8, GameID: 3},
+		}))
+		wtest.Must(t, c.Save(conn, []*Collection{
+			&Collection{ID: 1, Title: "Has games"},
+			&Collection{ID: 2, Title: "Empty"},
+		}))
+		wtest.Must(t, c.Save(conn, []*CollectionGame{
+			&CollectionGame{CollectionID: 1, GameID: 1},
+			&CollectionGame{CollectionID: 1, GameID: 2},
+		}))
+
+		// games with at least 3 uploads, with args in join, where and having
+		var games []*Game
+		wtest.Must(t, c.Select(conn, &games,
+			builder.Neq{"games.title": "nope"},
+			hades.Search{}.
+				JoinCond("uploads", builder.Expr("uploads.game_id = games.id AND uploads.id > ?", 0)).
+				GroupBy("games.id").
+				Having(builder.Expr("count(uploads.id) >= ?", 3)).
+				OrderBy("games.id ASC")))
+		assert.EqualValues(t, 2, len(games))
+		assert.EqualValues(t, 1, games[0].ID)
+		assert.EqualValues(t, 3, games[1].ID)
+
+		count, err := c.Query(&Game{}).
+			Search(hades.Search{}.Join("uploads", "uploads.game_id = games.id").GroupBy("games.id").Having(builder.Expr("count(uploads.id) >= ?", 4))).
+			Count(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, count)
+
+		// collections including those with no games
+		type row struct {
+			Title string
+			Games int64
+		}
+		var rows []row
+		wtest.Must(t, c.ExecWithSearch(conn,
+			builder.Select("collections.title", "count(collection_games.game_id) AS games").From("collections"),
+			hades.Search{}.
+				LeftJoin("collection_games", builder.Expr("collection_games.collection_id = collections.id")).
+				GroupBy("collections.id").
+				OrderBy("collections.id ASC"),
+			c.IntoRowsScannerByName(&rows, hades.UnknownColumnsError)))
+		assert.EqualValues(t, []row{{"Has games", 2}, {"Empty", 0}}, rows)
+
+		// games with uploads, once each
+		games = nil
+		wtest.Must(t, c.Select(conn, &games, builder.NewCond(), hades.Search{}.
+			Join("uploads", "uploads.game_id = games.id").
+			Distinct().
+			OrderBy("games.id ASC")))
+		assert.EqualValues(t, 3, len(games))
+
+		count, err = c.Query(&Game{}).Search(hades.Search{}.CrossJoin("collections").Distinct()).Count(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 3, count)
+
+		count, err = c.Query(&Game{}).Search(hades.Search{}.CrossJoin("collections")).Count(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 6, count)
+	})
+}
+
+func Test_SelectOrderByField(t *testing.T) {
+	type Game struct {
+		ID          int64
+		Title       string
+		UserID      int64
+		PublishedAt *time.Time
+	}
+
+	models := []interface{}{&Game{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		early := time.Date(2017, time.May, 1, 10, 0, 0, 0, time.UTC)
+		late := time.Date(2018, time.March, 14, 15, 9, 26, 0, time.UTC)
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 1, Title: "b", UserID: 1, PublishedAt: &early},
+			&Game{ID: 2, Title: "a", UserID: 2},
+			&Game{ID: 3, Title: "c", UserID: 1, PublishedAt: &late},
+		}))
+
+		ids := func(search hades.Search) []int64 {
+			t.Helper()
+			var games []*Game
+			wtest.Must(t, c.Select(conn, &games, builder.NewCond(), search))
+			var ids []int64
+			for _, g := range games {
+				ids = append(ids, g.ID)
+			}
+			return ids
+		}
+
+		assert.EqualValues(t, []int64{3, 1, 2}, ids(hades.Search{}.OrderByField("Title", hades.Desc)))
+		assert.EqualValues(t, []int64{2, 1, 3}, ids(hades.Search{}.OrderByField("published_at", hades.Asc)))
+		assert.EqualValues(t, []int64{1, 3, 2}, ids(hades.Search{}.OrderByField("PublishedAt", hades.Asc).NullsLast()))
+		assert.EqualValues(t, []int64{2, 3, 1}, ids(hades.Search{}.OrderByField("PublishedAt", hades.Desc).NullsFirst()))
+
+		var games []*Game
+		err := c.Select(conn, &games, builder.NewCond(), hades.Search{}.OrderByField("id; DROP TABLE games", hades.Asc))
+		assert.Error(t, err, "must refuse unknown fields")
+
+		var userIDs []int64
+		wtest.Must(t, c.Pluck(conn, &Game{}, "UserID", &userIDs, builder.NewCond(),
+			hades.Search{}.GroupByField("UserID").OrderByField("UserID", hades.Desc)))
+		assert.EqualValues(t, []int64{2, 1}, userIDs)
+
+		count, err := c.Query(&Game{}).GroupByField("UserID").Count(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count)
+	})
+}
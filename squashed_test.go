@@ -153,3 +153,50 @@ func Test_SquashedFull(t *testing.T) {
 		assert.EqualValues(t, FakeGameTraits{Ubiquitous: false, Storied: false}, u.Games[0].Traits)
 	})
 }
+
+func Test_SquashedColumns(t *testing.T) {
+	type BoneTraits struct {
+		Name     string
+		Goodness int64
+	}
+
+	type Bone struct {
+		ID     int64
+		Traits BoneTraits `hades:"squash"`
+	}
+
+	models := []interface{}{&Bone{}}
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.ExecRaw(conn, "CREATE UNIQUE INDEX bones_name ON bones (name)", nil))
+		wtest.Must(t, c.Save(conn, []*Bone{
+			{ID: 1, Traits: BoneTraits{Name: "cranium", Goodness: 3}},
+			{ID: 2, Traits: BoneTraits{Name: "humerus", Goodness: 98}},
+		}))
+
+		var names []string
+		wtest.Must(t, c.Pluck(conn, &Bone{}, "Name", &names, builder.NewCond(), hades.Search{}.OrderByField("Goodness", hades.Desc)))
+		assert.EqualValues(t, []string{"humerus", "cranium"}, names)
+
+		var best int64
+		found, err := c.Max(conn, &Bone{}, "goodness", &best, builder.NewCond(), hades.Search{})
+		wtest.Must(t, err)
+		assert.True(t, found)
+		assert.EqualValues(t, 98, best)
+
+		_, err = c.Update(conn, &Bone{}, hades.Where(builder.Eq{"id": 1}), map[string]interface{}{"Goodness": 4})
+		wtest.Must(t, err)
+
+		report := &hades.SaveReport{}
+		wtest.Must(t, c.Save(conn, &Bone{ID: 3, Traits: BoneTraits{Name: "cranium", Goodness: 5}},
+			hades.OnConflict(hades.OnConflictUpdate, "Name"), hades.Fields("Goodness"), hades.Report(report)))
+		assert.EqualValues(t, hades.RowUpdated, report.Rows[0].Outcome)
+
+		b := &Bone{}
+		wtest.Must(t, c.Get(conn, b, int64(1)))
+		assert.EqualValues(t, BoneTraits{Name: "cranium", Goodness: 5}, b.Traits)
+
+		var bones []*Bone
+		err = c.Select(conn, &bones, builder.NewCond(), hades.Search{}.Fields("Name"))
+		assert.Error(t, err, "must refuse to partially select squashed structs")
+	})
+}
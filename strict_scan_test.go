@@ -0,0 +1,136 @@
+package hades_test
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StrictScan(t *testing.T) {
+	type Upload struct {
+		ID       int64
+		Filename string
+		Size     uint8
+		Build    *int64
+	}
+
+	models := []interface{}{&Upload{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.ExecRaw(conn, `INSERT INTO uploads (id, filename, size, build) VALUES
+			(1, 'a.zip', 12, NULL),
+			(2, 'b.zip', NULL, 3)`, nil))
+
+		// lenient by default
+		var uploads []*Upload
+		wtest.Must(t, c.Select(conn, &uploads, builder.NewCond(), hades.Search{}.OrderBy("id ASC")))
+		assert.EqualValues(t, 2, len(uploads))
+		assert.EqualValues(t, 0, uploads[1].Size)
+
+		c.StrictScan = true
+
+		uploads = nil
+		err := c.Select(conn, &uploads, builder.NewCond(), hades.Search{}.OrderBy("id ASC"))
+		assert.Error(t, err, "must refuse NULL into non-pointer field")
+		assert.Contains(t, err.Error(), "row 1")
+		assert.Contains(t, err.Error(), "Upload")
+		assert.Contains(t, err.Error(), "Size")
+
+		u := &Upload{}
+		found, err := c.SelectOne(conn, u, builder.Eq{"id": 1})
+		wtest.Must(t, err)
+		assert.True(t, found)
+		assert.Nil(t, u.Build, "NULL is fine for pointer fields")
+
+		_, err = c.SelectOne(conn, u, builder.Eq{"id": 2})
+		assert.Error(t, err, "must refuse NULL into non-pointer field")
+		assert.Contains(t, err.Error(), "row 0")
+		assert.Contains(t, err.Error(), "Upload")
+		assert.Contains(t, err.Error(), "Size")
+
+		scan := func(query string) error {
+			var rows []Upload
+			return c.ExecRaw(conn, query, c.IntoRowsScanner(&rows))
+		}
+
+		wtest.Must(t, scan("SELECT id, filename, size, build FROM uploads WHERE id = 1"))
+
+		err = scan("SELECT id, size, filename, build FROM uploads WHERE id = 1")
+		assert.Error(t, err, "must refuse columns in the wrong order")
+		assert.Contains(t, err.Error(), "expected column filename")
+
+		err = scan("SELECT id, filename, size FROM uploads WHERE id = 1")
+		assert.Error(t, err, "must refuse missing columns")
+
+		err = scan("SELECT id, filename, size, build, 1 AS extra FROM uploads WHERE id = 1")
+		assert.Error(t, err, "must refuse extra columns")
+
+		err = scan("SELECT id, 42 AS filename, size, build FROM uploads WHERE id = 1")
+		assert.Error(t, err, "must refuse storage class mismatches")
+		assert.Contains(t, err.Error(), "Filename")
+
+		err = scan("SELECT '1' AS id, filename, size, build FROM uploads WHERE id = 1")
+		assert.Error(t, err, "must refuse text into integers")
+
+		err = scan("SELECT id, filename, 256 AS size, build FROM uploads WHERE id = 1")
+		assert.Error(t, err, "must refuse out-of-range values")
+		assert.Contains(t, err.Error(), "out of range")
+
+		err = scan("SELECT id, filename, -1 AS size, build FROM uploads WHERE id = 1")
+		assert.Error(t, err, "must refuse negative values for unsigned fields")
+	})
+}
+
+func Test_StrictScanUint64(t *testing.T) {
+	type Blob struct {
+		ID       int64
+		Checksum uint64
+	}
+
+	models := []interface{}{&Blob{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		c.StrictScan = true
+
+		wtest.Must(t, c.Save(conn, &Blob{ID: 1, Checksum: 1 << 62}))
+		b := &Blob{}
+		found, err := c.SelectOne(conn, b, builder.Eq{"id": 1})
+		wtest.Must(t, err)
+		assert.True(t, found)
+		assert.EqualValues(t, uint64(1<<62), b.Checksum)
+
+		// sqlite integers are signed, so this wraps around when stored
+		wtest.Must(t, c.Save(conn, &Blob{ID: 2, Checksum: 1 << 63}))
+		_, err = c.SelectOne(conn, b, builder.Eq{"id": 2})
+		assert.Error(t, err, "must refuse uint64 values that don't fit in an sqlite integer")
+		assert.Contains(t, err.Error(), "Checksum")
+	})
+}
+
+func Test_StrictScanSquashed(t *testing.T) {
+	type BoneTraits struct {
+		Name     string
+		Goodness uint8
+	}
+
+	type Bone struct {
+		ID     int64
+		Traits BoneTraits `hades:"squash"`
+	}
+
+	models := []interface{}{&Bone{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.ExecRaw(conn, `INSERT INTO bones (id, name, goodness) VALUES (1, 'femur', NULL)`, nil))
+
+		c.StrictScan = true
+		err := c.Get(conn, &Bone{}, int64(1))
+		assert.Error(t, err, "must refuse NULL into non-pointer field")
+		assert.Contains(t, err.Error(), "model hades_test.Bone")
+		assert.Contains(t, err.Error(), "field Traits.Goodness")
+	})
+}
@@ -0,0 +1,164 @@
+package hades
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-xorm/builder"
+)
+
+// Subquery is a query that can be used inside another one: as a
+// condition, with In or Exists, or as a common table expression,
+// with Search.With.
+type Subquery struct {
+	query string
+	args  []interface{}
+	err   error
+}
+
+// Subquery turns a query on model into a Subquery, which selects column
+// (a field name or a column name) from records of model matching cond:
+//
+//   // games that are in collection 12
+//   inCollection := c.Subquery(&CollectionGame{}, "GameID", builder.Eq{"collection_id": 12}, hades.Search{})
+//   err := c.Select(conn, &games, inCollection.In("games.id"), hades.Search{})
+//
+// Pass an empty column for subqueries only used with Exists.
+// Errors are returned when the subquery is used.
+func (c *Context) Subquery(model interface{}, column string, cond builder.Cond, search Search) Subquery {
+	ms := c.NewScope(model).GetModelStruct()
+
+	selected := "1"
+	if column != "" {
+		sf, err := ms.columnField(column)
+		if err != nil {
+			return Subquery{err: err}
+		}
+		selected = fmt.Sprintf("%s.%s", EscapeIdentifier(ms.TableName), EscapeIdentifier(sf.DBName))
+	}
+
+	search, err := search.resolveFields(c, ms)
+	if err != nil {
+		return Subquery{err: err}
+	}
+
+	query, args, err := search.ToSQL(builder.Select(selected).From(ms.TableName).Where(cond))
+	return Subquery{query: query, args: args, err: err}
+}
+
+// RawSubquery returns a Subquery for a hand-written SELECT statement,
+// for example to select from a common table expression.
+func RawSubquery(query string, args ...interface{}) Subquery {
+	return Subquery{query: query, args: args}
+}
+
+// ToSQL returns the subquery along with its arguments.
+func (sq Subquery) ToSQL() (string, []interface{}, error) {
+	return sq.query, sq.args, sq.err
+}
+
+// Union combines the results of sq and other, without duplicates.
+func (sq Subquery) Union(other Subquery) Subquery {
+	return sq.compound("UNION", other)
+}
+
+// UnionAll combines the results of sq and other, keeping duplicates.
+// It's typically used for the body of recursive common table expressions,
+// see Search.WithRecursive.
+func (sq Subquery) UnionAll(other Subquery) Subquery {
+	return sq.compound("UNION ALL", other)
+}
+
+func (sq Subquery) compound(op string, other Subquery) Subquery {
+	if sq.err != nil {
+		return sq
+	}
+	if other.err != nil {
+		return other
+	}
+
+	var args []interface{}
+	args = append(args, sq.args...)
+	args = append(args, other.args...)
+	return Subquery{
+		query: fmt.Sprintf("%s %s %s", sq.query, op, other.query),
+		args:  args,
+	}
+}
+
+// In returns a condition matching rows where column (a raw SQL expression,
+// like "games.id") is one of the values selected by sq.
+func (sq Subquery) In(column string) builder.Cond {
+	return subqueryCond{prefix: column + " IN", sq: sq}
+}
+
+// NotIn returns a condition matching rows where column (a raw SQL
+// expression) is none of the values selected by sq.
+func (sq Subquery) NotIn(column string) builder.Cond {
+	return subqueryCond{prefix: column + " NOT IN", sq: sq}
+}
+
+// Exists returns a condition that's true if sq selects at least one row.
+func (sq Subquery) Exists() builder.Cond {
+	return subqueryCond{prefix: "EXISTS", sq: sq}
+}
+
+// NotExists returns a condition that's true if sq selects no rows.
+func (sq Subquery) NotExists() builder.Cond {
+	return subqueryCond{prefix: "NOT EXISTS", sq: sq}
+}
+
+type subqueryCond struct {
+	prefix string
+	sq     Subquery
+}
+
+var _ builder.Cond = subqueryCond{}
+
+func (sc subqueryCond) WriteTo(w builder.Writer) error {
+	if sc.sq.err != nil {
+		return sc.sq.err
+	}
+	if _, err := fmt.Fprintf(w, "%s (%s)", sc.prefix, sc.sq.query); err != nil {
+		return err
+	}
+	w.Append(sc.sq.args...)
+	return nil
+}
+
+func (sc subqueryCond) And(conds ...builder.Cond) builder.Cond {
+	return builder.And(sc, builder.And(conds...))
+}
+
+func (sc subqueryCond) Or(conds ...builder.Cond) builder.Cond {
+	return builder.Or(sc, builder.Or(conds...))
+}
+
+func (sc subqueryCond) IsValid() bool {
+	return true
+}
+
+type cte struct {
+	name string
+	sq   Subquery
+}
+
+// withClause returns the WITH clause for ctes (with a trailing
+// space), along with its arguments.
+func withClause(ctes []cte, recursive bool) (string, []interface{}, error) {
+	var parts []string
+	var args []interface{}
+	for _, ct := range ctes {
+		if ct.sq.err != nil {
+			return "", nil, ct.sq.err
+		}
+		parts = append(parts, fmt.Sprintf("%s AS (%s)", ct.name, ct.sq.query))
+		args = append(args, ct.sq.args...)
+	}
+
+	keyword := "WITH"
+	if recursive {
+		keyword = "WITH RECURSIVE"
+	}
+	return fmt.Sprintf("%s %s ", keyword, strings.Join(parts, ", ")), args, nil
+}
@@ -0,0 +1,135 @@
+package hades_test
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Subquery(t *testing.T) {
+	type Game struct {
+		ID        int64
+		Title     string
+		Published bool
+	}
+
+	type Collection struct {
+		ID       int64
+		ParentID int64
+	}
+
+	type CollectionGame struct {
+		CollectionID int64 `hades:"primary_key"`
+		GameID       int64 `hades:"primary_key"`
+	}
+
+	models := []interface{}{&Game{}, &Collection{}, &CollectionGame{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, []*Game{
+			&Game{ID: 1, Title: "a", Published: true},
+			&Game{ID: 2, Title: "b", Published: true},
+			&Game{ID: 3, Title: "c"},
+			&Game{ID: 4, Title: "d", Published: true},
+			&Game{ID: 5, Title: "e", Published: true},
+		}))
+		// 10 > 20 > 30, and 40 on its own
+		wtest.Must(t, c.Save(conn, []*Collection{
+			&Collection{ID: 10},
+			&Collection{ID: 20, ParentID: 10},
+			&Collection{ID: 30, ParentID: 20},
+			&Collection{ID: 40},
+		}))
+		wtest.Must(t, c.Save(conn, []*CollectionGame{
+			&CollectionGame{CollectionID: 10, GameID: 1},
+			&CollectionGame{CollectionID: 20, GameID: 2},
+			&CollectionGame{CollectionID: 30, GameID: 3},
+			&CollectionGame{CollectionID: 40, GameID: 4},
+		}))
+
+		gameIDs := func(cond builder.Cond, search hades.Search) []int64 {
+			t.Helper()
+			var games []*Game
+			wtest.Must(t, c.Select(conn, &games, cond, search.OrderBy("games.id ASC")))
+			var ids []int64
+			for _, g := range games {
+				ids = append(ids, g.ID)
+			}
+			return ids
+		}
+
+		inCollection := c.Subquery(&CollectionGame{}, "GameID", builder.Eq{"collection_id": 40}, hades.Search{})
+		assert.EqualValues(t, []int64{4}, gameIDs(inCollection.In("games.id"), hades.Search{}))
+
+		inAny := c.Subquery(&CollectionGame{}, "GameID", builder.NewCond(), hades.Search{})
+		assert.EqualValues(t, []int64{5}, gameIDs(inAny.NotIn("games.id"), hades.Search{}))
+
+		inSame := c.Subquery(&CollectionGame{}, "", builder.Expr("collection_games.game_id = games.id"), hades.Search{})
+		assert.EqualValues(t, []int64{1, 2, 3, 4}, gameIDs(inSame.Exists(), hades.Search{}))
+		assert.EqualValues(t, []int64{5}, gameIDs(inSame.NotExists(), hades.Search{}))
+
+		// games in collection 20 and its descendants, with arguments
+		// in the CTE, in the subquery, and in the outer condition
+		tree := hades.RawSubquery("SELECT ?", 20).UnionAll(
+			c.Subquery(&Collection{}, "ID", builder.NewCond(),
+				hades.Search{}.Join("tree", "collections.parent_id = tree.id")))
+		inTree := c.Subquery(&CollectionGame{}, "GameID",
+			hades.RawSubquery("SELECT id FROM tree").In("collection_games.collection_id"),
+			hades.Search{})
+		treeSearch := hades.Search{}.WithRecursive("tree(id)", tree)
+
+		assert.EqualValues(t, []int64{2, 3}, gameIDs(inTree.In("games.id"), treeSearch))
+		assert.EqualValues(t, []int64{2}, gameIDs(builder.And(builder.Eq{"games.published": true}, inTree.In("games.id")), treeSearch))
+
+		count, err := c.Count(conn, &Game{}, inTree.In("games.id"), treeSearch)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count)
+
+		count, err = c.Count(conn, &Game{}, inCollection.In("games.id"))
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, count)
+
+		updated, err := c.Query(&Game{}).Search(treeSearch).Where(inTree.In("games.id")).Update(conn, builder.Eq{"published": false})
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, updated)
+		assert.EqualValues(t, []int64{1, 4, 5}, gameIDs(builder.Eq{"published": true}, hades.Search{}))
+
+		updated, err = c.Update(conn, &Game{}, hades.WhereWithSearch(inTree.In("games.id"), treeSearch), builder.Eq{"title": "in tree"})
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, updated)
+		count, err = c.Count(conn, &Game{}, builder.Eq{"title": "in tree"})
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count)
+
+		_, err = c.Update(conn, &Game{}, hades.WhereWithSearch(builder.Expr("1"), hades.Search{}.OrderBy("id")), builder.Eq{"title": "x"})
+		assert.Error(t, err, "must refuse searches with more than common table expressions")
+
+		_, err = c.Query(&Game{}).Search(treeSearch).Where(inTree.In("games.id")).Delete(conn)
+		wtest.Must(t, err)
+		assert.EqualValues(t, []int64{1, 4, 5}, gameIDs(builder.NewCond(), hades.Search{}))
+
+		deleted, err := c.Delete(conn, &CollectionGame{},
+			hades.RawSubquery("SELECT id FROM tree").In("collection_games.collection_id"), treeSearch)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, deleted)
+
+		_, err = c.Delete(conn, &Game{}, builder.Expr("1"), hades.Search{}.Limit(1))
+		assert.Error(t, err, "must refuse searches with more than common table expressions")
+
+		_, err = c.Delete(conn, &Game{}, inAny.NotIn("games.id"))
+		wtest.Must(t, err)
+		assert.EqualValues(t, []int64{1, 4}, gameIDs(builder.NewCond(), hades.Search{}))
+		count, err = c.Count(conn, &CollectionGame{}, builder.NewCond())
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count)
+
+		bad := c.Subquery(&CollectionGame{}, "Position", builder.NewCond(), hades.Search{})
+		var games []*Game
+		assert.Error(t, c.Select(conn, &games, bad.In("games.id"), hades.Search{}), "must report subquery errors")
+		assert.Error(t, c.Select(conn, &games, builder.NewCond(), hades.Search{}.With("x", bad)), "must report subquery errors in CTEs")
+	})
+}
@@ -0,0 +1,127 @@
+package hades
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/pkg/errors"
+)
+
+// TimeFormat is how time.Time values are stored in the database
+type TimeFormat string
+
+const (
+	// TimeFormatRFC3339 stores times as RFC3339 strings with nanoseconds
+	TimeFormatRFC3339 TimeFormat = "rfc3339"
+	// TimeFormatUnix stores times as integer seconds since the unix epoch
+	TimeFormatUnix TimeFormat = "unix"
+	// TimeFormatUnixMilli stores times as integer milliseconds since the unix epoch
+	TimeFormatUnixMilli TimeFormat = "unixmilli"
+	// TimeFormatSQLite stores times in UTC as "YYYY-MM-DD HH:MM:SS", the
+	// format used by SQLite's date and time functions, followed by fractional
+	// seconds (up to nanoseconds, without trailing zeros) if there are any.
+	TimeFormatSQLite TimeFormat = "sqlite"
+)
+
+var ValidTimeFormats = map[TimeFormat]bool{
+	TimeFormatRFC3339:   true,
+	TimeFormatUnix:      true,
+	TimeFormatUnixMilli: true,
+	TimeFormatSQLite:    true,
+}
+
+const sqliteTimeLayout = "2006-01-02 15:04:05.999999999"
+
+// time layouts accepted when reading, whatever the storage format.
+// fractional seconds are accepted even when the layout doesn't mention them.
+var lenientTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02",
+}
+
+func (c *Context) timeFormat(sf *StructField) TimeFormat {
+	if sf != nil {
+		if format, ok := sf.TagSettings[TagSettingTime]; ok {
+			return TimeFormat(format)
+		}
+	}
+	if c != nil && c.TimeFormat != "" {
+		return c.TimeFormat
+	}
+	return TimeFormatRFC3339
+}
+
+func (c *Context) encodeTime(tim time.Time, format TimeFormat) interface{} {
+	if c != nil && c.TimeUTC {
+		tim = tim.UTC()
+	}
+
+	switch format {
+	case TimeFormatUnix:
+		return tim.Unix()
+	case TimeFormatUnixMilli:
+		return tim.UnixNano() / int64(time.Millisecond)
+	case TimeFormatSQLite:
+		return tim.UTC().Format(sqliteTimeLayout)
+	default:
+		return tim.Format(time.RFC3339Nano)
+	}
+}
+
+// decodeTime reads a time from column col of stmt, accepting any of the
+// storage formats. Numbers are interpreted as unix timestamps, in milliseconds
+// if format is TimeFormatUnixMilli, in seconds otherwise, and decode to UTC
+// times like text without a time zone does. NULL decodes to the zero time.
+func (c *Context) decodeTime(stmt *sqlite.Stmt, col int, format TimeFormat) (time.Time, error) {
+	switch stmt.ColumnType(col) {
+	case sqlite.SQLITE_NULL:
+		return time.Time{}, nil
+	case sqlite.SQLITE_INTEGER:
+		return unixTime(stmt.ColumnInt64(col), format), nil
+	case sqlite.SQLITE_FLOAT:
+		return unixTimeFloat(stmt.ColumnFloat(col), format), nil
+	case sqlite.SQLITE_TEXT:
+		return parseTime(stmt.ColumnText(col), format)
+	}
+	return time.Time{}, errors.Errorf("cannot read a time from a %v column", stmt.ColumnType(col))
+}
+
+func unixTime(n int64, format TimeFormat) time.Time {
+	if format == TimeFormatUnixMilli {
+		return time.Unix(n/1000, (n%1000)*int64(time.Millisecond)).UTC()
+	}
+	return time.Unix(n, 0).UTC()
+}
+
+func unixTimeFloat(f float64, format TimeFormat) time.Time {
+	if format == TimeFormatUnixMilli {
+		f /= 1000
+	}
+	secs := math.Floor(f)
+	return time.Unix(int64(secs), int64((f-secs)*float64(time.Second))).UTC()
+}
+
+func parseTime(text string, format TimeFormat) (time.Time, error) {
+	for _, layout := range lenientTimeLayouts {
+		tim, err := time.Parse(layout, text)
+		if err == nil {
+			return tim, nil
+		}
+	}
+
+	if n, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return unixTime(n, format), nil
+	}
+	if f, err := strconv.ParseFloat(text, 64); err == nil {
+		return unixTimeFloat(f, format), nil
+	}
+
+	return time.Time{}, errors.Errorf("could not parse %q as a time", text)
+}
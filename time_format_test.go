@@ -0,0 +1,106 @@
+package hades_test
+
+import (
+	"testing"
+	"time"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_TimeFormats(t *testing.T) {
+	type Event struct {
+		ID         int64
+		HappenedAt time.Time
+		UnixAt     time.Time  `hades:"time:unix"`
+		MilliAt    time.Time  `hades:"time:unixmilli"`
+		SqliteAt   *time.Time `hades:"time:sqlite"`
+	}
+
+	models := []interface{}{&Event{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		tim := time.Date(2018, time.March, 14, 15, 9, 26, 535000000, time.FixedZone("CET", 3600))
+		wtest.Must(t, c.Save(conn, &Event{
+			ID:         1,
+			HappenedAt: tim,
+			UnixAt:     tim,
+			MilliAt:    tim,
+			SqliteAt:   &tim,
+		}))
+
+		wtest.Must(t, c.ExecRaw(conn, "SELECT happened_at, unix_at, milli_at, sqlite_at FROM events WHERE id = 1", func(stmt *sqlite.Stmt) error {
+			assert.EqualValues(t, "2018-03-14T15:09:26.535+01:00", stmt.ColumnText(0))
+			assert.EqualValues(t, sqlite.SQLITE_INTEGER, stmt.ColumnType(1))
+			assert.EqualValues(t, tim.Unix(), stmt.ColumnInt64(1))
+			assert.EqualValues(t, tim.Unix()*1000+535, stmt.ColumnInt64(2))
+			assert.EqualValues(t, "2018-03-14 14:09:26.535", stmt.ColumnText(3))
+			return nil
+		}))
+
+		e := &Event{}
+		found, err := c.SelectOne(conn, e, builder.Eq{"id": 1})
+		wtest.Must(t, err)
+		assert.True(t, found)
+		assert.True(t, tim.Equal(e.HappenedAt))
+		assert.True(t, tim.Truncate(time.Second).Equal(e.UnixAt))
+		assert.True(t, tim.Equal(e.MilliAt))
+		assert.True(t, tim.Equal(*e.SqliteAt))
+
+		// rows written by other tools are read whatever the field's format
+		wtest.Must(t, c.ExecRaw(conn, `INSERT INTO events (id, happened_at, unix_at, milli_at, sqlite_at)
+			VALUES (2, 1521036566, '2018-03-14 14:09:26', '2018-03-14T14:09:26Z', NULL)`, nil))
+
+		found, err = c.SelectOne(conn, e, builder.Eq{"id": 2})
+		wtest.Must(t, err)
+		assert.True(t, found)
+		expected := time.Date(2018, time.March, 14, 14, 9, 26, 0, time.UTC)
+		assert.True(t, expected.Equal(e.HappenedAt))
+		assert.True(t, expected.Equal(e.UnixAt))
+		assert.True(t, expected.Equal(e.MilliAt))
+		assert.Nil(t, e.SqliteAt)
+		assert.EqualValues(t, time.UTC, e.HappenedAt.Location(), "unix timestamps must decode to UTC")
+		assert.EqualValues(t, time.UTC, e.UnixAt.Location())
+
+		wtest.Must(t, c.ExecRaw(conn, `INSERT INTO events (id, happened_at) VALUES (3, 'last tuesday')`, nil))
+		_, err = c.SelectOne(conn, e, builder.Eq{"id": 3})
+		assert.Error(t, err, "must refuse unparseable times")
+		assert.Contains(t, err.Error(), "HappenedAt")
+		assert.Contains(t, err.Error(), "last tuesday")
+
+		// context-wide default and UTC normalization
+		c.TimeFormat = hades.TimeFormatSQLite
+		c.TimeUTC = true
+		wtest.Must(t, c.Save(conn, &Event{
+			ID:         4,
+			HappenedAt: tim,
+		}))
+		wtest.Must(t, c.Save(conn, &Event{
+			ID:         5,
+			HappenedAt: tim.Truncate(time.Second),
+		}))
+		wtest.Must(t, c.ExecRaw(conn, "SELECT happened_at FROM events WHERE id = 5", func(stmt *sqlite.Stmt) error {
+			assert.EqualValues(t, "2018-03-14 14:09:26", stmt.ColumnText(0), "whole seconds have no fractional part")
+			return nil
+		}))
+		wtest.Must(t, c.ExecRaw(conn, "SELECT happened_at, unix_at FROM events WHERE id = 4", func(stmt *sqlite.Stmt) error {
+			assert.EqualValues(t, "2018-03-14 14:09:26.535", stmt.ColumnText(0))
+			assert.EqualValues(t, sqlite.SQLITE_INTEGER, stmt.ColumnType(1), "tagged fields keep their own format")
+			return nil
+		}))
+	})
+}
+
+func Test_TimeFormatInvalid(t *testing.T) {
+	type Appointment struct {
+		ID int64
+		At time.Time `hades:"time:whenever"`
+	}
+
+	assert.Panics(t, func() {
+		hades.NewContext(nil, &Appointment{})
+	})
+}
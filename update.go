@@ -2,6 +2,7 @@ package hades
 
 import (
 	"reflect"
+	"strings"
 
 	"crawshaw.io/sqlite"
 	"github.com/go-xorm/builder"
@@ -13,7 +14,8 @@ type WhereCond interface {
 }
 
 type whereImpl struct {
-	cond builder.Cond
+	cond   builder.Cond
+	search Search
 }
 
 func (wt whereImpl) Cond() builder.Cond {
@@ -24,14 +26,168 @@ func Where(cond builder.Cond) WhereCond {
 	return whereImpl{cond: cond}
 }
 
-func (c *Context) Update(conn *sqlite.Conn, model interface{}, where WhereCond, updates ...builder.Eq) error {
+// WhereWithSearch is like Where, but with common table expressions from
+// search, which cond may use. search can't have other clauses:
+//
+//   tree := hades.Search{}.WithRecursive("tree(id)", subtree)
+//   n, err := c.Update(conn, &Collection{},
+//     hades.WhereWithSearch(builder.Expr("id IN (SELECT id FROM tree)"), tree),
+//     map[string]interface{}{"Hidden": true})
+func WhereWithSearch(cond builder.Cond, search Search) WhereCond {
+	return whereImpl{cond: cond, search: search}
+}
+
+// Update sets columns of all records of model matching where, and
+// returns how many records were changed. Each update is one of:
+//
+//   - a map[string]interface{} of field names (or column names) to values
+//   - a *Model (or Model) whose non-zero fields are set, except for
+//     primary keys, which are never changed that way
+//   - an Increment, like hades.Increment("DownloadCount", 1)
+//   - a builder.Eq of raw column names to values, which isn't checked
+//
+// For example:
+//
+//   n, err := c.Update(conn, &Game{},
+//     hades.Where(builder.Eq{"id": 12}),
+//     map[string]interface{}{"Title": "Overland", "PublishedAt": now},
+//     hades.Increment("DownloadCount", 1))
+//
+// Values are converted like DBValue does, using the tag settings of
+// the fields they're assigned to. Unknown fields are refused. Pass
+// WhereWithSearch as where for conditions that use common table
+// expressions.
+func (c *Context) Update(conn *sqlite.Conn, model interface{}, where WhereCond, updates ...interface{}) (int64, error) {
 	modelType := reflect.TypeOf(model)
 	scope := c.ScopeMap.ByType(modelType)
 	if scope == nil {
-		return errors.Errorf("%v is not a know model type", modelType)
+		return 0, errors.Errorf("%v is not a know model type", modelType)
+	}
+
+	var search Search
+	if wi, ok := where.(whereImpl); ok {
+		search = wi.search
+	}
+	err := search.checkOnlyCTEs("Update")
+	if err != nil {
+		return 0, err
+	}
+
+	eq, err := c.updateEq(scope.GetModelStruct(), updates)
+	if err != nil {
+		return 0, err
 	}
 
 	tableName := scope.TableName()
-	b := builder.Update(updates...).Where(where.Cond()).Into(tableName)
-	return c.Exec(conn, b, nil)
+	b := builder.Update(eq).Where(where.Cond()).Into(tableName)
+	err = c.ExecWithSearch(conn, b, search, nil)
+	if err != nil {
+		return 0, err
+	}
+	return int64(conn.Changes()), nil
+}
+
+// IncrementExpr is an update that adds to a column, see Increment.
+type IncrementExpr struct {
+	field string
+	delta interface{}
+}
+
+// Increment returns an update for Update that adds delta (which
+// may be negative) to the column of field, given by field name or
+// column name, without reading it first.
+func Increment(field string, delta interface{}) IncrementExpr {
+	return IncrementExpr{field: field, delta: delta}
+}
+
+// updateEq translates updates, as accepted by Update,
+// into assignments to columns of ms.
+func (c *Context) updateEq(ms *ModelStruct, updates []interface{}) (builder.Eq, error) {
+	eq := make(builder.Eq)
+	// builder.Eq keys are used as-is, other columns are escaped,
+	// so they're compared unquoted, and case-insensitively like SQLite does
+	seen := make(map[string]bool)
+	set := func(column string, value interface{}) error {
+		key := strings.ToLower(strings.Trim(column, "\"`[]"))
+		if seen[key] {
+			return errors.Errorf("column %s of %v is updated twice", column, ms.ModelType)
+		}
+		seen[key] = true
+		eq[column] = value
+		return nil
+	}
+
+	for _, update := range updates {
+		switch update := update.(type) {
+		case nil:
+			return nil, errors.Errorf("Update can't update %v with nil", ms.ModelType)
+		case builder.Eq:
+			for column, value := range update {
+				err := set(column, value)
+				if err != nil {
+					return nil, err
+				}
+			}
+		case map[string]interface{}:
+			for name, value := range update {
+				sf, err := ms.columnField(name)
+				if err != nil {
+					return nil, errors.WithMessage(err, "in Update")
+				}
+				err = set(EscapeIdentifier(sf.DBName), c.encodeValue(sf, value))
+				if err != nil {
+					return nil, err
+				}
+			}
+		case IncrementExpr:
+			sf, err := ms.columnField(update.field)
+			if err != nil {
+				return nil, errors.WithMessage(err, "in Increment")
+			}
+			column := EscapeIdentifier(sf.DBName)
+			err = set(column, builder.Expr(column+" + ?", update.delta))
+			if err != nil {
+				return nil, err
+			}
+		default:
+			val := reflect.ValueOf(update)
+			if val.Kind() == reflect.Ptr && val.IsNil() {
+				return nil, errors.Errorf("Update can't update %v with a nil %v", ms.ModelType, val.Type())
+			}
+			if val.Kind() == reflect.Ptr {
+				val = val.Elem()
+			}
+			if val.Type() != ms.ModelType {
+				return nil, errors.Errorf("Update can't update %v with a %v", ms.ModelType, reflect.TypeOf(update))
+			}
+
+			var processField func(sf *StructField, val reflect.Value) error
+			processField = func(sf *StructField, val reflect.Value) error {
+				field := val.FieldByName(sf.Name)
+				if sf.IsSquashed {
+					for _, nsf := range sf.SquashedFields {
+						err := processField(nsf, field)
+						if err != nil {
+							return err
+						}
+					}
+				}
+				if !sf.IsNormal || sf.IsPrimaryKey || field.IsZero() {
+					return nil
+				}
+				return set(EscapeIdentifier(sf.DBName), c.encodeValue(sf, field.Interface()))
+			}
+			for _, sf := range ms.StructFields {
+				err := processField(sf, val)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	if len(eq) == 0 {
+		return nil, errors.Errorf("nothing to update in %v", ms.ModelType)
+	}
+	return eq, nil
 }
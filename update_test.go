@@ -2,6 +2,7 @@ package hades_test
 
 import (
 	"testing"
+	"time"
 
 	"crawshaw.io/sqlite"
 	"github.com/go-xorm/builder"
@@ -39,10 +40,12 @@ func Test_Update(t *testing.T) {
 		wtest.Must(t, err)
 		assert.EqualValues(t, 2, count)
 
-		wtest.Must(t, c.Update(conn, &Mistake{},
+		updated, err := c.Update(conn, &Mistake{},
 			hades.Where(builder.Eq{"id": 1}),
 			builder.Eq{"body": "rewrote almost everything"},
-		))
+		)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, updated)
 
 		var m Mistake
 		var found bool
@@ -51,10 +54,12 @@ func Test_Update(t *testing.T) {
 		assert.True(t, found)
 		assert.EqualValues(t, "rewrote almost everything", m.Body)
 
-		wtest.Must(t, c.Update(conn, &Mistake{},
+		updated, err = c.Update(conn, &Mistake{},
 			hades.Where(builder.Expr("1")),
 			builder.Eq{"body": "nothing"},
-		))
+		)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, updated)
 
 		found, err = c.SelectOne(conn, &m, builder.Eq{"id": 1})
 		wtest.Must(t, err)
@@ -67,3 +72,94 @@ func Test_Update(t *testing.T) {
 		assert.EqualValues(t, "nothing", m.Body)
 	})
 }
+
+func Test_UpdateFields(t *testing.T) {
+	type Game struct {
+		ID            int64
+		Title         string
+		DownloadCount int64
+		PublishedAt   *time.Time
+	}
+
+	models := []interface{}{&Game{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, []*Game{
+			{ID: 1, Title: "Overland", DownloadCount: 10},
+			{ID: 2, Title: "Sauerbraten", DownloadCount: 20},
+		}))
+
+		get := func(id int64) *Game {
+			g := &Game{}
+			wtest.Must(t, c.Get(conn, g, id))
+			return g
+		}
+
+		publishedAt := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+		updated, err := c.Update(conn, &Game{},
+			hades.Where(builder.Eq{"id": 1}),
+			map[string]interface{}{"Title": "Overland 2", "published_at": &publishedAt},
+			hades.Increment("DownloadCount", 5),
+		)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, updated)
+
+		g := get(1)
+		assert.EqualValues(t, "Overland 2", g.Title)
+		assert.EqualValues(t, 15, g.DownloadCount)
+		assert.True(t, publishedAt.Equal(*g.PublishedAt))
+
+		// only non-zero fields of structs are set
+		updated, err = c.Update(conn, &Game{},
+			hades.Where(builder.Expr("1")),
+			&Game{Title: "Renamed"},
+			hades.Increment("download_count", -1),
+		)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, updated)
+
+		g = get(2)
+		assert.EqualValues(t, "Renamed", g.Title)
+		assert.EqualValues(t, 19, g.DownloadCount)
+
+		// primary keys of structs are left alone
+		updated, err = c.Update(conn, &Game{},
+			hades.Where(builder.Eq{"id": 2}),
+			&Game{ID: 3, Title: "Sauerbraten"},
+		)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, updated)
+		assert.EqualValues(t, "Sauerbraten", get(2).Title)
+
+		updated, err = c.Update(conn, &Game{},
+			hades.Where(builder.Eq{"id": 3}),
+			map[string]interface{}{"Title": "Nobody"},
+		)
+		wtest.Must(t, err)
+		assert.EqualValues(t, 0, updated)
+
+		updated, err = c.Query(&Game{}).Where(builder.Eq{"id": 2}).Update(conn, hades.Increment("DownloadCount", 1))
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, updated)
+		assert.EqualValues(t, 20, get(2).DownloadCount)
+
+		_, err = c.Update(conn, &Game{}, hades.Where(builder.Expr("1")), map[string]interface{}{"Titel": "Typo"})
+		assert.Error(t, err, "must refuse unknown fields")
+		_, err = c.Update(conn, &Game{}, hades.Where(builder.Expr("1")), hades.Increment("Downloads", 1))
+		assert.Error(t, err, "must refuse unknown fields")
+		_, err = c.Update(conn, &Game{}, hades.Where(builder.Expr("1")), &Game{Title: "A"}, map[string]interface{}{"Title": "B"})
+		assert.Error(t, err, "must refuse updating a column twice")
+		_, err = c.Update(conn, &Game{}, hades.Where(builder.Expr("1")), builder.Eq{"TITLE": "A"}, map[string]interface{}{"Title": "B"})
+		assert.Error(t, err, "must refuse updating a column twice, however it's spelled")
+		_, err = c.Update(conn, &Game{}, hades.Where(builder.Expr("1")), nil)
+		assert.Error(t, err, "must refuse nil updates")
+		_, err = c.Update(conn, &Game{}, hades.Where(builder.Expr("1")), (*Game)(nil))
+		assert.Error(t, err, "must refuse nil updates")
+		_, err = c.Query(&Game{}).Where(builder.Expr("1")).GroupBy("title").Update(conn, &Game{Title: "A"})
+		assert.Error(t, err, "must refuse grouping in update")
+		_, err = c.Update(conn, &Game{}, hades.Where(builder.Expr("1")), &Game{})
+		assert.Error(t, err, "must refuse empty updates")
+		_, err = c.Update(conn, &Game{}, hades.Where(builder.Expr("1")), &struct{ Title string }{Title: "A"})
+		assert.Error(t, err, "must refuse other structs")
+	})
+}
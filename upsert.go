@@ -3,6 +3,7 @@ package hades
 import (
 	"fmt"
 	"reflect"
+	"sort"
 	"strings"
 
 	"crawshaw.io/sqlite"
@@ -11,6 +12,12 @@ import (
 
 // TODO: cache me
 func (scope *Scope) ToSets() []string {
+	return scope.toSets(nil)
+}
+
+// toSets returns assignments for the given columns, or all
+// non-primary columns if columns is nil
+func (scope *Scope) toSets(columns map[string]bool) []string {
 	var sets []string
 
 	var processField func(sf *StructField)
@@ -29,6 +36,10 @@ func (scope *Scope) ToSets() []string {
 			return
 		}
 
+		if columns != nil && !columns[sf.DBName] {
+			return
+		}
+
 		name := EscapeIdentifier(sf.DBName)
 		sets = append(sets, fmt.Sprintf("%s=excluded.%s", name, name))
 	}
@@ -41,7 +52,39 @@ func (scope *Scope) ToSets() []string {
 }
 
 func (c *Context) Upsert(conn *sqlite.Conn, scope *Scope, rec reflect.Value) error {
-	eq := scope.ToEq(rec)
+	return c.upsert(conn, scope, rec, nil, nil)
+}
+
+// upsert upserts rec, only writing the given columns (and primary
+// keys), or all of them if columns is nil. Conflicts are handled
+// according to cf.
+func (c *Context) upsert(conn *sqlite.Conn, scope *Scope, rec reflect.Value, columns map[string]bool, cf *conflict) error {
+	recs := []reflect.Value{rec}
+
+	mode := cf.getMode()
+	if mode == OnConflictUpdate {
+		if cf.updatesChanges() {
+			outcome, err := c.saveChanges(conn, scope, rec, columns)
+			if err != nil {
+				return err
+			}
+			if outcome != 0 {
+				cf.record(recs, []RowOutcome{outcome})
+				return nil
+			}
+		}
+
+		// records loaded with Search.Fields only update what was loaded
+		columns = intersectColumns(columns, c.loadedColumns(rec))
+	} else if mode == OnConflictReplace {
+		err := cf.checkColumns(intersectColumns(columns, c.loadedColumns(rec)))
+		if err != nil {
+			return err
+		}
+	}
+	columns = cf.withTarget(columns)
+
+	eq := scope.toEq(rec, columns)
 
 	b := builder.Insert(eq).Into(scope.TableName())
 
@@ -50,23 +93,172 @@ func (c *Context) Upsert(conn *sqlite.Conn, scope *Scope, rec reflect.Value) err
 		return err
 	}
 
-	sets := scope.ToSets()
+	sets := scope.toSets(columns)
+	verb, clause := cf.sql(scope, sets)
+	sql = verb + strings.TrimPrefix(sql, "INSERT")
+	if clause != "" {
+		sql = fmt.Sprintf("%s %s", sql, clause)
+	}
+
+	outcomes, err := c.plannedOutcomes(conn, scope, cf, recs, len(sets) > 0)
+	if err != nil {
+		return err
+	}
+	err = c.ExecRaw(conn, sql, nil, args...)
+	if err != nil {
+		return err
+	}
+	cf.record(recs, outcomes)
+	if c.TrackChanges && mode != OnConflictIgnore {
+		c.snapshot(rec, columns, true)
+	}
+	return nil
+}
+
+// intersectColumns returns the columns in both a and b, where
+// nil means all columns.
+func intersectColumns(a, b map[string]bool) map[string]bool {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	res := make(map[string]bool)
+	for column := range a {
+		if b[column] {
+			res[column] = true
+		}
+	}
+	return res
+}
+
+// upsertMany upserts recs, which are *Model values, using multi-row
+// INSERT statements with as many rows as maxSqlVars allows. Records
+// loaded with Search.Fields are upserted one by one, since each of them
+// only updates the columns it has. columns and cf are as for upsert.
+func (c *Context) upsertMany(conn *sqlite.Conn, scope *Scope, recs []reflect.Value, columns map[string]bool, cf *conflict) error {
+	var batch []reflect.Value
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := c.upsertBatch(conn, scope, batch, columns, cf)
+		batch = nil
+		return err
+	}
+
+	for _, rec := range recs {
+		if c.tracked(rec) && cf.updatesChanges() {
+			// tracked records only update what changed
+			err := flush()
+			if err != nil {
+				return err
+			}
+			outcome, err := c.saveChanges(conn, scope, rec, columns)
+			if err != nil {
+				return err
+			}
+			if outcome != 0 {
+				cf.record([]reflect.Value{rec}, []RowOutcome{outcome})
+				continue
+			}
+		}
+
+		if c.loadedColumns(rec) != nil {
+			// keep the order records were passed in, in case
+			// some of them share a primary key.
+			err := flush()
+			if err != nil {
+				return err
+			}
+			err = c.upsert(conn, scope, rec, columns, cf)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		batch = append(batch, rec)
+	}
+	return flush()
+}
+
+// upsertBatch upserts full records in chunks. All full chunks share the
+// same query, so the statement conn prepares for the first one is
+// reused for the others. Remaining records are saved in chunks whose
+// size is a power of two, so that only a few distinct statements are
+// ever prepared, whatever the number of records saved.
+func (c *Context) upsertBatch(conn *sqlite.Conn, scope *Scope, recs []reflect.Value, columns map[string]bool, cf *conflict) error {
+	err := cf.checkColumns(columns)
+	if err != nil {
+		return err
+	}
+	columns = cf.withTarget(columns)
+
+	first := scope.toEq(recs[0], columns)
+	var names []string
+	for name := range first {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	rowsPerChunk := maxSqlVars / len(names)
+	if rowsPerChunk < 1 {
+		rowsPerChunk = 1
+	}
+
+	placeholders := "(" + strings.TrimSuffix(strings.Repeat("?,", len(names)), ",") + ")"
+	sets := scope.toSets(columns)
+	verb, clause := cf.sql(scope, sets)
+	queries := make(map[int]string)
+
+	for len(recs) > 0 {
+		n := rowsPerChunk
+		if len(recs) < n {
+			n = 1
+			for n*2 <= len(recs) {
+				n *= 2
+			}
+		}
+
+		query, ok := queries[n]
+		if !ok {
+			rows := strings.TrimSuffix(strings.Repeat(placeholders+",", n), ",")
+			query = fmt.Sprintf("%s INTO %s (%s) VALUES %s",
+				verb,
+				EscapeIdentifier(scope.TableName()),
+				strings.Join(names, ","),
+				rows,
+			)
+			if clause != "" {
+				query = fmt.Sprintf("%s %s", query, clause)
+			}
+			queries[n] = query
+		}
 
-	if len(sets) == 0 {
-		sql = fmt.Sprintf("%s ON CONFLICT DO NOTHING",
-			sql,
-		)
-	} else {
-		var pfNames []string
-		for _, pf := range scope.GetModelStruct().PrimaryFields {
-			pfNames = append(pfNames, pf.DBName)
+		args := make([]interface{}, 0, n*len(names))
+		for _, rec := range recs[:n] {
+			eq := scope.toEq(rec, columns)
+			for _, name := range names {
+				args = append(args, eq[name])
+			}
 		}
 
-		sql = fmt.Sprintf("%s ON CONFLICT(%s) DO UPDATE SET %s",
-			sql,
-			strings.Join(pfNames, ","),
-			strings.Join(sets, ","),
-		)
+		outcomes, err := c.plannedOutcomes(conn, scope, cf, recs[:n], len(sets) > 0)
+		if err != nil {
+			return err
+		}
+		err = c.ExecRaw(conn, query, nil, args...)
+		if err != nil {
+			return err
+		}
+		cf.record(recs[:n], outcomes)
+		if c.TrackChanges && cf.getMode() != OnConflictIgnore {
+			for _, rec := range recs[:n] {
+				c.snapshot(rec, columns, true)
+			}
+		}
+		recs = recs[n:]
 	}
-	return c.ExecRaw(conn, sql, nil, args...)
+	return nil
 }
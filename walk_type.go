@@ -30,6 +30,9 @@ type ManyToMany struct {
 
 	// SourceKey => []JoinRec{DestinKey, Record}
 	Values map[interface{}][]JoinRec
+
+	// columns written for records, nil if all of them are
+	columns map[string]bool
 }
 
 func (c *Context) NewManyToMany(JoinTable string, SourceForeignKeys, DestinationForeignKeys []JoinTableForeignKey) (*ManyToMany, error) {
@@ -110,6 +113,9 @@ type RecordInfo struct {
 	Relationship *Relationship
 	ManyToMany   *ManyToMany
 	ModelStruct  *ModelStruct
+
+	// columns saved, set by Fields and Omit. nil if all of them are.
+	columns map[string]bool
 }
 
 func (ri *RecordInfo) Name() string {
@@ -150,7 +156,13 @@ func (c *Context) WalkType(riMap RecordInfoMap, field AssocField, atyp reflect.T
 	}
 
 	// visit specified assocs
+	var columnsFields []*columnsField
 	for _, assoc := range field.Children() {
+		if cf, ok := assoc.(*columnsField); ok {
+			columnsFields = append(columnsFields, cf)
+			continue
+		}
+
 		sf, ok := ms.StructFieldsByName[assoc.Name()]
 		if !ok {
 			return nil, errors.Errorf("No field '%s' in %s", assoc.Name(), atyp)
@@ -199,6 +211,12 @@ func (c *Context) WalkType(riMap RecordInfoMap, field AssocField, atyp reflect.T
 		ri.Children = append(ri.Children, child)
 	}
 
+	columns, err := savedColumns(ms, columnsFields)
+	if err != nil {
+		return nil, err
+	}
+	ri.columns = columns
+
 	riMap[atyp] = ri
 	return ri, nil
 }
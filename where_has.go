@@ -0,0 +1,107 @@
+package hades
+
+import (
+	"fmt"
+	"strings"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/pkg/errors"
+)
+
+// Exists returns whether at least one record of model matches cond.
+// It stops at the first match, so it's cheaper than checking Count > 0.
+func (c *Context) Exists(conn *sqlite.Conn, model interface{}, cond builder.Cond) (bool, error) {
+	ms := c.NewScope(model).GetModelStruct()
+	return c.exists(conn, ms, cond, Search{})
+}
+
+// WhereHas returns a condition matching records of model that have at
+// least one associated record, through association assoc, matching cond.
+// It can be used anywhere a condition can, for example:
+//
+//   // users who have at least one published game
+//   err := c.Select(conn, &users, c.WhereHas(&User{}, "Games", builder.Eq{"games.published": true}), hades.Search{})
+//
+// Pass builder.NewCond() to match records with any associated record.
+// If assoc isn't a valid association, the error is returned when the
+// condition is used.
+func (c *Context) WhereHas(model interface{}, assoc string, cond builder.Cond) builder.Cond {
+	return c.newHasCond(model, assoc, cond, false)
+}
+
+// WhereHasNot is the opposite of WhereHas: it matches records of model
+// that have no associated record matching cond.
+//
+//   // collections with no games
+//   err := c.Select(conn, &collections, c.WhereHasNot(&Collection{}, "Games", builder.NewCond()), hades.Search{})
+func (c *Context) WhereHasNot(model interface{}, assoc string, cond builder.Cond) builder.Cond {
+	return c.newHasCond(model, assoc, cond, true)
+}
+
+// hasCond is a correlated EXISTS subquery on an association.
+type hasCond struct {
+	a    *association
+	cond builder.Cond
+	not  bool
+	err  error
+}
+
+var _ builder.Cond = hasCond{}
+
+func (c *Context) newHasCond(model interface{}, assoc string, cond builder.Cond, not bool) builder.Cond {
+	if cond == nil {
+		cond = builder.NewCond()
+	}
+	ms := c.NewScope(model).GetModelStruct()
+	a, err := c.association(ms, assoc)
+	if err == nil && a.assoc.TableName == ms.TableName {
+		err = errors.Errorf("association %s of %v is on the same table, which WhereHas doesn't support", assoc, ms.ModelType)
+	}
+	return hasCond{a: a, cond: cond, not: not, err: err}
+}
+
+func (hc hasCond) WriteTo(w builder.Writer) error {
+	if hc.err != nil {
+		return hc.err
+	}
+
+	a := hc.a
+	fromLinks, toLinks := a.links(a.ms.TableName, a.joinTable, a.assoc.TableName)
+
+	var sub *builder.Builder
+	if a.joinTable != "" {
+		sub = builder.Select("1").From(a.joinTable).
+			InnerJoin(a.assoc.TableName, strings.Join(toLinks, " AND "))
+	} else {
+		sub = builder.Select("1").From(a.assoc.TableName)
+	}
+	sub = sub.Where(builder.And(builder.Expr(strings.Join(fromLinks, " AND ")), hc.cond))
+
+	query, args, err := sub.ToSQL()
+	if err != nil {
+		return err
+	}
+
+	op := "EXISTS"
+	if hc.not {
+		op = "NOT EXISTS"
+	}
+	if _, err := fmt.Fprintf(w, "%s (%s)", op, query); err != nil {
+		return err
+	}
+	w.Append(args...)
+	return nil
+}
+
+func (hc hasCond) And(conds ...builder.Cond) builder.Cond {
+	return builder.And(hc, builder.And(conds...))
+}
+
+func (hc hasCond) Or(conds ...builder.Cond) builder.Cond {
+	return builder.Or(hc, builder.Or(conds...))
+}
+
+func (hc hasCond) IsValid() bool {
+	return true
+}
@@ -0,0 +1,103 @@
+package hades_test
+
+import (
+	"testing"
+
+	"crawshaw.io/sqlite"
+	"github.com/go-xorm/builder"
+	"github.com/itchio/hades"
+	"github.com/itchio/wharf/wtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WhereHas(t *testing.T) {
+	type Game struct {
+		ID        int64
+		UserID    int64
+		Published bool
+	}
+
+	type User struct {
+		ID    int64
+		Name  string
+		Games []*Game
+	}
+
+	type Upload struct {
+		ID     int64
+		GameID int64
+		Game   *Game
+	}
+
+	type Collection struct {
+		ID    int64
+		Games []*Game `hades:"many_to_many:collection_games"`
+	}
+
+	type CollectionGame struct {
+		CollectionID int64 `hades:"primary_key"`
+		GameID       int64 `hades:"primary_key"`
+	}
+
+	models := []interface{}{&Game{}, &User{}, &Upload{}, &Collection{}, &CollectionGame{}}
+
+	withContext(t, models, func(conn *sqlite.Conn, c *hades.Context) {
+		wtest.Must(t, c.Save(conn, []*User{
+			&User{ID: 1, Name: "leafo", Games: []*Game{{ID: 10, Published: true}, {ID: 11}}},
+			&User{ID: 2, Name: "fasterthanlime", Games: []*Game{{ID: 20}}},
+			&User{ID: 3, Name: "lurker"},
+		}, hades.Assoc("Games")))
+		wtest.Must(t, c.Save(conn, []*Upload{
+			&Upload{ID: 100, GameID: 10},
+			&Upload{ID: 200, GameID: 20},
+		}))
+		wtest.Must(t, c.Save(conn, []*Collection{
+			&Collection{ID: 1000, Games: []*Game{{ID: 10, UserID: 1, Published: true}}},
+			&Collection{ID: 2000},
+		}, hades.AssocReplace("Games")))
+
+		userNames := func(cond builder.Cond) []string {
+			t.Helper()
+			var users []*User
+			wtest.Must(t, c.Select(conn, &users, cond, hades.Search{}.OrderBy("id ASC")))
+			var names []string
+			for _, u := range users {
+				names = append(names, u.Name)
+			}
+			return names
+		}
+
+		assert.EqualValues(t, []string{"leafo", "fasterthanlime"}, userNames(c.WhereHas(&User{}, "Games", builder.NewCond())))
+		assert.EqualValues(t, []string{"leafo"}, userNames(c.WhereHas(&User{}, "Games", builder.Eq{"games.published": true})))
+		assert.EqualValues(t, []string{"lurker"}, userNames(c.WhereHasNot(&User{}, "Games", builder.NewCond())))
+		assert.EqualValues(t, []string{"fasterthanlime"}, userNames(builder.And(
+			c.WhereHas(&User{}, "Games", builder.NewCond()),
+			c.WhereHasNot(&User{}, "Games", builder.Eq{"games.published": true}),
+		)))
+
+		count, err := c.Count(conn, &Upload{}, c.WhereHas(&Upload{}, "Game", builder.Eq{"games.user_id": 1}))
+		wtest.Must(t, err)
+		assert.EqualValues(t, 1, count, "must handle belongs_to")
+
+		var collections []*Collection
+		wtest.Must(t, c.Select(conn, &collections, c.WhereHasNot(&Collection{}, "Games", builder.NewCond()), hades.Search{}))
+		assert.EqualValues(t, 1, len(collections))
+		assert.EqualValues(t, 2000, collections[0].ID, "must handle many_to_many")
+
+		ok, err := c.Exists(conn, &Collection{}, c.WhereHas(&Collection{}, "Games", builder.Eq{"games.id": 10}))
+		wtest.Must(t, err)
+		assert.True(t, ok)
+		ok, err = c.Exists(conn, &Collection{}, c.WhereHas(&Collection{}, "Games", builder.Eq{"games.id": 20}))
+		wtest.Must(t, err)
+		assert.False(t, ok)
+
+		_, err = c.Delete(conn, &User{}, c.WhereHasNot(&User{}, "Games", builder.NewCond()))
+		wtest.Must(t, err)
+		count, err = c.Count(conn, &User{}, builder.NewCond())
+		wtest.Must(t, err)
+		assert.EqualValues(t, 2, count, "must work with Delete")
+
+		err = c.Select(conn, &collections, c.WhereHas(&Collection{}, "Owner", builder.NewCond()), hades.Search{})
+		assert.Error(t, err, "must refuse unknown associations")
+	})
+}